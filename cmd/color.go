@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"os"
+
+	"golang.org/x/term"
+)
+
+// ansiColors maps colour names to ANSI SGR codes.
+var ansiColors = map[string]string{
+	"red":     "1;31",
+	"green":   "32",
+	"yellow":  "33",
+	"blue":    "34",
+	"magenta": "35",
+	"cyan":    "36",
+	"bold":    "1",
+}
+
+// paint wraps s in the named colour if f is a terminal and NO_COLOR is not
+// set. Unknown colours leave s unchanged.
+func paint(f *os.File, color, s string) string {
+	if os.Getenv("NO_COLOR") != "" || !term.IsTerminal(int(f.Fd())) {
+		return s
+	}
+	return colorize(color, s, "", "")
+}
+
+// colorize wraps s in the named colour, with the escape sequences enclosed
+// in open and close (for shell prompts that must not count them as
+// printed). Unknown colours leave s unchanged.
+func colorize(color, s, open, close string) string {
+	code, ok := ansiColors[color]
+	if !ok {
+		return s
+	}
+	return open + "\x1b[" + code + "m" + close + s + open + "\x1b[0m" + close
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/consol-lee/nks-ctx/pkg/config"
+	"github.com/consol-lee/nks-ctx/pkg/kubeconfig"
+	"github.com/consol-lee/nks-ctx/pkg/ncp"
+)
+
+// settings holds plugin defaults from config.yaml / .nks-ctx.yaml.
+var settings = &config.Config{}
+
+// initSettings loads the plugin config and resolves the effective flags.
+func initSettings() error {
+	if err := loadSettings(); err != nil {
+		return err
+	}
+	return resolveFlags()
+}
+
+// loadSettings loads the plugin config into settings. On error settings keeps
+// its defaults.
+func loadSettings() error {
+	s, err := config.Load()
+	if err != nil {
+		return err
+	}
+	settings = s
+	return nil
+}
+
+// resolveFlags resolves the effective profile (--profile, then NCLOUD_PROFILE,
+// then the config file) and output format from the flags and settings.
+func resolveFlags() error {
+	if profileFlag == "" {
+		profileFlag = os.Getenv("NCLOUD_PROFILE")
+	}
+	if profileFlag == "" {
+		profileFlag = settings.Profile
+	}
+	if outputFlag == "" {
+		outputFlag = settings.Output
+	}
+	if outputFlag == "" {
+		outputFlag = "text"
+	}
+	if outputFlag != "text" && outputFlag != "json" {
+		return fmt.Errorf("unsupported output format %q (use text or json)", outputFlag)
+	}
+	return nil
+}
+
+// kubeconfigPath returns KUBECONFIG, then the configured kubeconfig, then ~/.kube/config.
+func kubeconfigPath() string {
+	if os.Getenv("KUBECONFIG") == "" && settings.Kubeconfig != "" {
+		return settings.Kubeconfig
+	}
+	return kubeconfig.DefaultPath()
+}
+
+func newManager() (*kubeconfig.Manager, error) {
+	return kubeconfig.NewManagerForPath(kubeconfigPath())
+}
+
+// filesLayout reports whether clusters are synced to one kubeconfig file each.
+func filesLayout() bool {
+	return settings.Layout == config.LayoutFiles
+}
+
+// kubeconfigPaths returns the files kubectl should see: the managed
+// kubeconfig, the rest of KUBECONFIG and, in the files layout, every indexed
+// per-cluster file.
+func kubeconfigPaths() []string {
+	return kubeconfigPathsFor(func(kubeconfig.IndexEntry) bool { return true })
+}
+
+// kubeconfigPathsFor is kubeconfigPaths with only the per-cluster files whose
+// index entry keep accepts.
+func kubeconfigPathsFor(keep func(kubeconfig.IndexEntry) bool) []string {
+	paths := []string{kubeconfigPath()}
+	seen := map[string]bool{paths[0]: true}
+	add := func(p string) {
+		if p != "" && !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+	for _, p := range filepath.SplitList(os.Getenv("KUBECONFIG")) {
+		add(p)
+	}
+	if filesLayout() {
+		if idx, err := kubeconfig.LoadIndex(kubeconfig.FilesDir()); err == nil {
+			for _, e := range idx.Entries {
+				if keep(e) {
+					add(e.File)
+				}
+			}
+		}
+	}
+	return paths
+}
+
+// newMergedManager loads every file from kubeconfigPaths for lookups and
+// switching; switching writes current-context to the managed kubeconfig.
+func newMergedManager() (*kubeconfig.Manager, error) {
+	return kubeconfig.NewManagerForPaths(kubeconfigPaths())
+}
+
+// newAuthenticator uses --authenticator-path, then NKS_CTX_AUTHENTICATOR, then
+// the configured authenticatorPath, and otherwise searches for the binary.
+func newAuthenticator(profile string) *ncp.Authenticator {
+	path := authenticatorPathFlag
+	if path == "" {
+		path = os.Getenv("NKS_CTX_AUTHENTICATOR")
+	}
+	if path == "" {
+		path = settings.AuthenticatorPath
+	}
+	if path == "" {
+		return ncp.NewAuthenticator(profile)
+	}
+	return ncp.NewAuthenticatorForPath(profile, path)
+}
+
+// filterRegions keeps clusters in the configured regions (all if none are configured).
+func filterRegions(clusters []ncp.Cluster, regions []string) []ncp.Cluster {
+	if len(regions) == 0 {
+		return clusters
+	}
+	allowed := make(map[string]bool, len(regions))
+	for _, r := range regions {
+		allowed[r] = true
+	}
+	var kept []ncp.Cluster
+	for _, c := range clusters {
+		if allowed[c.Region] {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// contextName renders the configured nameTemplate for a cluster.
+func contextName(tmpl string, meta kubeconfig.ClusterMeta) (string, error) {
+	t, err := template.New("nameTemplate").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid nameTemplate: %w", err)
+	}
+	var b bytes.Buffer
+	data := struct{ Name, UUID, Region, Profile string }{meta.ClusterName, meta.ClusterUUID, meta.Region, meta.Profile}
+	if err := t.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("invalid nameTemplate: %w", err)
+	}
+	return b.String(), nil
+}
+
+// tagClusters records nks-ctx metadata on the contexts of synced clusters and
+// applies the configured nameTemplate. It reports whether anything changed;
+// the caller saves.
+func tagClusters(manager *kubeconfig.Manager, clusters []ncp.Cluster, profile string) (bool, error) {
+	changed := false
+	for _, cluster := range clusters {
+		ctxName := findClusterContext(manager, cluster)
+		if ctxName == "" {
+			continue
+		}
+
+		meta := kubeconfig.ClusterMeta{
+			ClusterName: cluster.Name,
+			ClusterUUID: cluster.UUID,
+			Region:      cluster.Region,
+			Profile:     profile,
+		}
+		if existing := manager.Meta(ctxName); existing == nil || *existing != meta {
+			if err := manager.SetMeta(ctxName, meta); err != nil {
+				return false, err
+			}
+			changed = true
+		}
+		for _, name := range manager.DerivedContexts(cluster.UUID) {
+			existing := manager.Meta(name)
+			derived := meta
+			derived.Derived, derived.ReadOnly = true, existing.ReadOnly
+			if *existing != derived {
+				if err := manager.SetMeta(name, derived); err != nil {
+					return false, err
+				}
+				changed = true
+			}
+		}
+
+		if settings.NameTemplate == "" {
+			continue
+		}
+		name, err := contextName(settings.NameTemplate, meta)
+		if err != nil {
+			return false, err
+		}
+		if name != ctxName {
+			if err := manager.RenameContext(ctxName, name); err != nil {
+				fmt.Fprintf(os.Stderr, "  Warning: cannot rename %s: %v\n", ctxName, err)
+				continue
+			}
+			changed = true
+		}
+	}
+	return changed, nil
+}
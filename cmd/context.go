@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"github.com/consol-lee/nks-ctx/pkg/kubeconfig"
+)
+
+var (
+	contextNamespace string
+	contextAlias     string
+)
+
+var contextCmd = &cobra.Command{
+	Use:   "context",
+	Short: "Manage contexts derived from synced clusters",
+	Long: `Manage derived contexts: extra contexts for a synced cluster that reuse
+its cluster and user entries with their own namespace.
+
+Derived contexts are tagged with the cluster's nks-ctx metadata, so refresh
+keeps them and prune removes them together with their cluster. They are
+listed and completed under their cluster.`,
+}
+
+var contextCreateCmd = &cobra.Command{
+	Use:   "create <cluster> --namespace <namespace> [--name <alias>]",
+	Short: "Create a context for one namespace of a cluster",
+	Long: `Create a context for one namespace of a synced cluster, named
+<cluster>/<namespace> unless --name is given:
+
+  kubectl nks-ctx context create prod-kr --namespace payments
+  kubectl nks-ctx prod-kr/payments`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeClusterNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if contextNamespace == "" {
+			return fmt.Errorf("--namespace is required")
+		}
+		manager, err := newMergedManager()
+		if err != nil {
+			return fmt.Errorf("failed to read kubeconfig: %w", err)
+		}
+		parent, err := manager.FindContext(args[0])
+		if err != nil {
+			return fmt.Errorf(
+				"context not found for '%s'.\nRun 'kubectl nks-ctx' first to sync clusters.",
+				args[0],
+			)
+		}
+		meta := manager.Meta(parent)
+		if meta == nil {
+			return fmt.Errorf("context '%s' is not managed by nks-ctx; run 'kubectl nks-ctx' to sync it", parent)
+		}
+		name := contextAlias
+		if name == "" {
+			name = kubeconfig.DerivedName(meta.ClusterName, contextNamespace)
+		}
+		if slices.Contains(manager.ListContextNames(), name) {
+			return fmt.Errorf("context '%s' already exists in kubeconfig", name)
+		}
+
+		if err := checkNamespace(cmd.Context(), manager, parent, contextNamespace); err != nil {
+			return err
+		}
+
+		path := manager.Origin(parent)
+		owner, err := kubeconfig.NewManagerForPath(path)
+		if err != nil {
+			return fmt.Errorf("failed to read kubeconfig: %w", err)
+		}
+		if err := owner.CreateDerived(parent, name, contextNamespace); err != nil {
+			return err
+		}
+		if err := owner.Save(); err != nil {
+			return fmt.Errorf("failed to update kubeconfig: %w", err)
+		}
+		fmt.Printf("Created context \"%s\" (namespace \"%s\") in %s\n", name, contextNamespace, path)
+		return nil
+	},
+}
+
+var contextDeleteCmd = &cobra.Command{
+	Use:   "delete <context>",
+	Short: "Delete a derived context",
+	Args:  cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 || initSettings() != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		manager, err := newMergedManager()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var names []string
+		for _, name := range manager.ManagedContexts() {
+			if manager.IsDerived(name) {
+				names = append(names, name)
+			}
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		manager, err := newMergedManager()
+		if err != nil {
+			return fmt.Errorf("failed to read kubeconfig: %w", err)
+		}
+		name := args[0]
+		if !manager.IsDerived(name) {
+			return fmt.Errorf("'%s' is not a derived context; only contexts made with 'context create' can be deleted", name)
+		}
+
+		path := manager.Origin(name)
+		owner, err := kubeconfig.NewManagerForPath(path)
+		if err != nil {
+			return fmt.Errorf("failed to read kubeconfig: %w", err)
+		}
+		if err := owner.RemoveContext(name); err != nil {
+			return err
+		}
+		if err := owner.Save(); err != nil {
+			return fmt.Errorf("failed to update kubeconfig: %w", err)
+		}
+		fmt.Printf("Deleted context \"%s\"\n", name)
+		return nil
+	},
+}
+
+func init() {
+	contextCreateCmd.Flags().StringVarP(&contextNamespace, "namespace", "n", "", "Namespace of the new context")
+	contextCreateCmd.Flags().StringVar(&contextAlias, "name", "", "Context name (default: <cluster>/<namespace>)")
+	contextCreateCmd.RegisterFlagCompletionFunc("namespace", completeNamespaces)
+
+	contextCmd.AddCommand(contextCreateCmd, contextDeleteCmd)
+	rootCmd.AddCommand(contextCmd)
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/consol-lee/nks-ctx/pkg/ncp"
+)
+
+var credentialsCmd = &cobra.Command{
+	Use:   "credentials",
+	Short: "Inspect NCP credential resolution",
+}
+
+var credentialsWhichCmd = &cobra.Command{
+	Use:   "which",
+	Short: "Show which credential provider supplies the keys",
+	Long: `Resolve credentials through the provider chain and report which provider
+supplied them. Providers are tried in order:
+
+  env                 NCLOUD_ACCESS_KEY / NCLOUD_SECRET_KEY
+  profile             ncloud_access_key_id / ncloud_secret_access_key in ~/.ncloud/configure
+  credential_process  command in the profile that prints JSON credentials
+  static_file         JSON file named by NCLOUD_CREDENTIALS_FILE
+
+The secret key is redacted.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := ncp.LoadConfig(profileFlag)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Provider:   %s\n", cfg.Source)
+		fmt.Printf("Profile:    %s\n", cfg.Profile)
+		fmt.Printf("Access key: %s\n", cfg.AccessKey)
+		fmt.Printf("Secret key: %s\n", ncp.RedactSecret(cfg.SecretKey))
+		fmt.Printf("API URL:    %s\n", cfg.ApiURL)
+		if cfg.Region != "" {
+			fmt.Printf("Region:     %s\n", cfg.Region)
+		}
+		if cfg.SessionToken != "" {
+			fmt.Printf("Session:    %s\n", ncp.RedactSecret(cfg.SessionToken))
+		}
+		if !cfg.Expiration.IsZero() {
+			fmt.Printf("Expires:    %s\n", cfg.Expiration.Local().Format(time.RFC3339))
+		}
+		return nil
+	},
+}
+
+func init() {
+	credentialsCmd.AddCommand(credentialsWhichCmd)
+	rootCmd.AddCommand(credentialsCmd)
+}
@@ -0,0 +1,287 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/consol-lee/nks-ctx/pkg/config"
+	"github.com/consol-lee/nks-ctx/pkg/doctor"
+	"github.com/consol-lee/nks-ctx/pkg/kubeconfig"
+	"github.com/consol-lee/nks-ctx/pkg/ncp"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// settingsErr is the plugin config error seen by doctor, which reports it
+// instead of aborting.
+var settingsErr error
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose common setup problems",
+	Long: `Run a checklist over everything nks-ctx depends on and print a
+pass/warn/fail table with a suggested fix for each problem:
+
+  config         plugin config.yaml / .nks-ctx.yaml
+  configure      ~/.ncloud/configure syntax and permissions
+  credentials    which provider supplies the keys
+  authenticator  ncp-iam-authenticator path and version
+  kubeconfig     load, merge and write access
+  endpoint       reachability and authentication per regional NKS endpoint
+  clock skew     offset to the API gateway clock (NCP allows 5 minutes)
+  contexts       health of the contexts nks-ctx manages
+
+Use -o json to attach the report to a support ticket; it contains no secrets.
+The command exits non-zero if any check fails.`,
+	Args: cobra.NoArgs,
+	// A broken config is one failed check; the rest still run with the
+	// profile and output format from the flags and environment.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		settingsErr = loadSettings()
+		return resolveFlags()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		report := doctor.NewReport()
+
+		checkSettings(report)
+		checkConfigureFile(report)
+		cfg := checkCredentials(report)
+		checkAuthenticator(report)
+		manager := checkKubeconfig(report)
+		if cfg != nil {
+			client := ncp.NewClientFromConfig(cfg)
+			checkEndpoints(report, client)
+			checkClockSkew(report, client)
+		} else {
+			report.Skip("endpoint", "no credentials")
+			report.Skip("clock skew", "no credentials")
+		}
+		checkContexts(report, manager)
+
+		var err error
+		if outputFlag == "json" {
+			err = report.WriteJSON(os.Stdout)
+		} else {
+			err = report.WriteText(os.Stdout)
+		}
+		if err != nil {
+			return err
+		}
+		if n := report.Count(doctor.Fail); n > 0 {
+			return fmt.Errorf("%d check(s) failed", n)
+		}
+		return nil
+	},
+}
+
+func init() {
+	doctorCmd.Flags().StringVarP(&outputFlag, "output", "o", "", "Output format: text or json")
+	rootCmd.AddCommand(doctorCmd)
+}
+
+func checkSettings(r *doctor.Report) {
+	if settingsErr != nil {
+		r.Fail("config", oneLine(settingsErr.Error()), "fix or remove "+config.DefaultPath())
+		return
+	}
+	if len(settings.Sources) == 0 {
+		r.Pass("config", "no config files (defaults)")
+		return
+	}
+	r.Pass("config", strings.Join(settings.Sources, ", "))
+}
+
+func checkConfigureFile(r *doctor.Report) {
+	path := ncp.DefaultConfigPath()
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		r.Skip("configure", path+" not present")
+		return
+	}
+	if err != nil {
+		r.Fail("configure", oneLine(err.Error()), "check permissions of "+path)
+		return
+	}
+
+	file, err := ncp.OpenConfigFile(path)
+	if err != nil {
+		r.Fail("configure", oneLine(err.Error()), "fix the line reported in "+path)
+		return
+	}
+	if mode := info.Mode().Perm(); mode&0077 != 0 {
+		r.Warn("configure", fmt.Sprintf("%s is readable by others (mode %04o)", path, mode), "chmod 600 "+path)
+	}
+	for _, w := range file.Warnings() {
+		r.Warn("configure", w, "edit "+path+" or use 'kubectl nks-ctx profile'")
+	}
+	r.Pass("configure", fmt.Sprintf("%s: %d profile(s)", path, len(file.Profiles())))
+}
+
+func checkCredentials(r *doctor.Report) *ncp.Config {
+	cfg, err := ncp.DefaultChain().Retrieve(profileFlag)
+	if err != nil {
+		r.Fail("credentials", oneLine(err.Error()), "add keys with 'kubectl nks-ctx profile add' or set NCLOUD_ACCESS_KEY/NCLOUD_SECRET_KEY")
+		return nil
+	}
+
+	detail := fmt.Sprintf("profile %s from %s (access key %s)", cfg.Profile, cfg.Source, cfg.AccessKey)
+	if !cfg.Expiration.IsZero() && cfg.Refresh == nil && cfg.ExpiresWithin(5*time.Minute) {
+		r.Warn("credentials", detail+", expiring "+cfg.Expiration.Local().Format(time.RFC3339), "renew the session token")
+	} else {
+		r.Pass("credentials", detail)
+	}
+	return cfg
+}
+
+func checkAuthenticator(r *doctor.Report) {
+	authenticator := newAuthenticator(profileFlag)
+	if !authenticator.IsInstalled() {
+		r.Fail("authenticator", "ncp-iam-authenticator not found at "+authenticator.Path(), "install it from https://guide.ncloud-docs.com/docs/nks-nkstoken or set --authenticator-path")
+		return
+	}
+	version, err := authenticator.CheckVersion()
+	if err != nil {
+		r.Fail("authenticator", oneLine(err.Error()), "upgrade from https://guide.ncloud-docs.com/docs/nks-nkstoken")
+		return
+	}
+	r.Pass("authenticator", fmt.Sprintf("%s %s (minimum %s)", authenticator.Path(), version, ncp.MinAuthenticatorVersion))
+}
+
+func checkKubeconfig(r *doctor.Report) *kubeconfig.Manager {
+	if list := filepath.SplitList(os.Getenv("KUBECONFIG")); len(list) > 1 {
+		rules := &clientcmd.ClientConfigLoadingRules{Precedence: list}
+		if _, err := rules.Load(); err != nil {
+			r.Fail("kubeconfig", "merging KUBECONFIG failed: "+oneLine(err.Error()), "fix or remove the broken file from KUBECONFIG")
+		} else {
+			r.Warn("kubeconfig", fmt.Sprintf("KUBECONFIG lists %d files; nks-ctx writes a single file", len(list)), "set kubeconfig in "+config.DefaultPath()+" and keep KUBECONFIG to one file")
+		}
+		return nil
+	}
+
+	path := kubeconfigPath()
+	manager, err := newManager()
+	if err != nil {
+		r.Fail("kubeconfig", oneLine(err.Error()), "fix the YAML in "+path+" or restore a backup")
+		return nil
+	}
+	if err := writable(path); err != nil {
+		r.Fail("kubeconfig", path+" is not writable: "+oneLine(err.Error()), "check ownership and permissions of "+path)
+		return manager
+	}
+	r.Pass("kubeconfig", fmt.Sprintf("%s: %d context(s), %d managed", path, len(manager.ListContextNames()), len(manager.ManagedContexts())))
+	return manager
+}
+
+func checkEndpoints(r *doctor.Report, client *ncp.Client) {
+	for _, endpoint := range client.Endpoints() {
+		name := "endpoint " + strings.TrimPrefix(endpoint, "https://")
+		err := client.ValidateEndpoint(endpoint)
+		var apiErr *ncp.APIError
+		switch {
+		case err == nil:
+			r.Pass(name, "reachable, credentials accepted")
+		case errors.As(err, &apiErr) && apiErr.Unauthorized():
+			r.Fail(name, fmt.Sprintf("credentials rejected (status %d)", apiErr.StatusCode), "check the keys with 'kubectl nks-ctx profile validate'")
+		case errors.As(err, &apiErr):
+			r.Warn(name, fmt.Sprintf("unexpected status %d", apiErr.StatusCode), "retry later; the region may be unavailable for this account")
+		default:
+			r.Fail(name, "unreachable: "+oneLine(err.Error()), "check network access and HTTPS_PROXY")
+		}
+	}
+}
+
+func checkClockSkew(r *doctor.Report, client *ncp.Client) {
+	reports, err := client.MeasureSkew()
+	if err != nil {
+		r.Warn("clock skew", "could not measure: "+oneLine(err.Error()), "check network access to the API gateway")
+		return
+	}
+	for _, s := range reports {
+		name := "clock skew " + s.Host
+		detail := fmt.Sprintf("%s (compensating %s)", formatSkew(s.Offset), formatSkew(s.Stored))
+		switch {
+		case s.Skew() <= 30*time.Second:
+			r.Pass(name, detail)
+		case s.Residual() < ncp.MaxSkew:
+			r.Warn(name, detail, "sync the system clock with NTP")
+		default:
+			r.Fail(name, detail, "sync the system clock with NTP; NCP rejects requests more than 5 minutes off")
+		}
+	}
+}
+
+func checkContexts(r *doctor.Report, manager *kubeconfig.Manager) {
+	if manager == nil {
+		r.Skip("contexts", "kubeconfig not loaded")
+		return
+	}
+	managed := manager.ManagedContexts()
+	if len(managed) == 0 {
+		r.Warn("contexts", "no contexts managed by nks-ctx", "run 'kubectl nks-ctx' to sync clusters")
+		return
+	}
+
+	healthy := 0
+	for _, name := range managed {
+		if problems := manager.Problems(name); len(problems) > 0 {
+			r.Fail("context "+name, strings.Join(problems, "; "), "run 'kubectl nks-ctx repair', or 'kubectl nks-ctx' to resync the cluster")
+			continue
+		}
+		healthy++
+	}
+	if healthy > 0 {
+		r.Pass("contexts", fmt.Sprintf("%d of %d managed context(s) healthy", healthy, len(managed)))
+	}
+}
+
+// writable checks that path can be written, or created if it does not exist.
+func writable(path string) error {
+	if f, err := os.OpenFile(path, os.O_WRONLY, 0); err == nil {
+		return f.Close()
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	f, err := os.CreateTemp(dir, ".nks-ctx-doctor-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// oneLine flattens multi-line messages so they fit in a table cell.
+func oneLine(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, strings.TrimSuffix(line, ":"))
+		}
+	}
+	return strings.Join(lines, "; ")
+}
+
+// formatSkew renders an offset with its sign, rounded to the Date header's
+// one-second resolution.
+func formatSkew(d time.Duration) string {
+	d = d.Round(time.Second)
+	if d >= 0 {
+		return "+" + d.String()
+	}
+	return d.String()
+}
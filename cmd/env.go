@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/consol-lee/nks-ctx/pkg/kubeconfig"
+	"github.com/consol-lee/nks-ctx/pkg/selector"
+)
+
+var envSelector string
+
+var envCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Print an export KUBECONFIG=... line for per-cluster kubeconfig files",
+	Long: `In the files layout (layout: files in config.yaml) each cluster is synced to
+its own kubeconfig under ~/.kube/nks/<profile>/<name>.yaml. This command
+prints a shell line that points KUBECONFIG at the managed kubeconfig followed
+by the selected per-cluster files, so kubectl sees them all:
+
+  eval "$(kubectl nks-ctx env)"
+  eval "$(kubectl nks-ctx env --selector 'profile=finance,region=KR')"
+
+The managed kubeconfig comes first, so it keeps current-context and switching
+with 'kubectl nks-ctx <cluster>' or 'kubectl config use-context' works as in
+the merged layout.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := selector.Parse(envSelector)
+		if err != nil {
+			return err
+		}
+		idx, err := kubeconfig.LoadIndex(kubeconfig.FilesDir())
+		if err != nil {
+			return err
+		}
+		entries := idx.Select(s, clusterStatuses())
+		if len(entries) == 0 {
+			fmt.Fprintf(os.Stderr, "No per-cluster kubeconfig files in %s match; sync with layout: files first.\n", idx.Path())
+		}
+
+		paths := []string{kubeconfigPath()}
+		for _, e := range entries {
+			paths = append(paths, e.File)
+		}
+		fmt.Printf("export KUBECONFIG=%s\n", shellQuote(strings.Join(paths, string(filepath.ListSeparator))))
+		return nil
+	},
+}
+
+func init() {
+	envCmd.Flags().StringVarP(&envSelector, "selector", "l", "", "Include clusters matching a selector, e.g. 'region=KR,name=~prod-.*'")
+	rootCmd.AddCommand(envCmd)
+}
+
+// shellQuote single-quotes s for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// kubeconfigEnvIncludes reports whether KUBECONFIG lists the files of all entries.
+func kubeconfigEnvIncludes(entries []kubeconfig.IndexEntry) bool {
+	listed := make(map[string]bool)
+	for _, p := range filepath.SplitList(os.Getenv("KUBECONFIG")) {
+		listed[p] = true
+	}
+	for _, e := range entries {
+		if !listed[e.File] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"os/exec"
+
+	"github.com/spf13/cobra"
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec <cluster-name> -- <command> [args...]",
+	Short: "Run a command against a cluster without switching context",
+	Long: `Run a command with KUBECONFIG pointing at a temporary kubeconfig that holds
+only the cluster's context, and NKS_CTX_CLUSTER set to the cluster name. The
+cluster is looked up like 'kubectl nks-ctx <cluster>'; current-context is
+never changed, so scripts can target clusters without affecting other
+terminals or each other.
+
+Stdio is passed through, signals are forwarded and nks-ctx exits with the
+command's exit status.
+
+Examples:
+  kubectl nks-ctx exec prod-api -- kubectl get pods -A
+  kubectl nks-ctx exec prod-api -- helm list -A
+  kubectl nks-ctx exec prod-api -- k9s`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if cmd.ArgsLenAtDash() != 1 || len(args) < 2 {
+			return fmt.Errorf("usage: kubectl nks-ctx exec <cluster-name> -- <command> [args...]")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := exec.LookPath(args[1])
+		if err != nil {
+			return err
+		}
+
+		kc, err := isolatedKubeconfig(args[0])
+		if err != nil {
+			return err
+		}
+		defer kc.remove()
+
+		c := exec.Command(path, args[2:]...)
+		c.Args[0] = args[1]
+		c.Env = kc.environ()
+		return runChild(c)
+	},
+	ValidArgsFunction: completeClusterNames,
+}
+
+func init() {
+	rootCmd.AddCommand(execCmd)
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/consol-lee/nks-ctx/pkg/kubeconfig"
+	"github.com/consol-lee/nks-ctx/pkg/ncp"
+	"github.com/consol-lee/nks-ctx/pkg/selector"
+)
+
+var (
+	exportFile       string
+	exportSelector   string
+	exportPinProfile string
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export [cluster-name...]",
+	Short: "Write a standalone kubeconfig for one or more clusters",
+	Long: `Write a self-contained kubeconfig holding only the given clusters' cluster,
+user and context entries, with certificates embedded and current-context set
+to the first cluster. Clusters are looked up like 'kubectl nks-ctx <cluster>';
+--selector picks them by label instead (name, context, region, profile,
+status):
+
+  kubectl nks-ctx export prod-api -o prod.yaml
+  kubectl nks-ctx export --selector 'region=KR,name=~prod-.*' -o prod-kr.yaml
+
+The authenticator command is reduced to its base name so it resolves through
+PATH on the receiving machine, and profile pinning is removed so the users
+take whatever credentials are available there. --pin-profile pins them to a
+named profile instead. The file is written with 0600 permissions; without
+-o it is printed to stdout.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && exportSelector == "" {
+			return fmt.Errorf("specify cluster names or --selector")
+		}
+		manager, err := newMergedManager()
+		if err != nil {
+			return fmt.Errorf("failed to read kubeconfig: %w", err)
+		}
+
+		names, err := exportContexts(manager, args)
+		if err != nil {
+			return err
+		}
+
+		var opts kubeconfig.ExportOptions
+		if exportPinProfile != "" {
+			opts.Pin = &kubeconfig.ExecPin{Profile: exportPinProfile}
+		}
+		exported, err := manager.Export(names, opts)
+		if err != nil {
+			return err
+		}
+		data, err := exported.Marshal()
+		if err != nil {
+			return err
+		}
+
+		if exportFile == "" || exportFile == "-" {
+			_, err := os.Stdout.Write(data)
+			return err
+		}
+		if err := writePrivateFile(exportFile, data); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d context(s) to %s\n", len(names), exportFile)
+		return nil
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringVarP(&exportFile, "output", "o", "", "File to write (default: stdout)")
+	exportCmd.Flags().StringVarP(&exportSelector, "selector", "l", "", "Export clusters matching a selector, e.g. 'region=KR,name=~prod-.*'")
+	exportCmd.Flags().StringVar(&exportPinProfile, "pin-profile", "", "Pin exported users to this NCP profile")
+	rootCmd.AddCommand(exportCmd)
+}
+
+// exportContexts resolves cluster names and --selector to context names,
+// keeping the order they were given in and dropping duplicates.
+func exportContexts(manager *kubeconfig.Manager, clusterNames []string) ([]string, error) {
+	var names []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	for _, clusterName := range clusterNames {
+		contextName, err := manager.FindContext(clusterName)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"context not found for '%s'.\nRun 'kubectl nks-ctx' first to sync clusters.",
+				clusterName,
+			)
+		}
+		add(contextName)
+	}
+
+	if exportSelector != "" {
+		s, err := selector.Parse(exportSelector)
+		if err != nil {
+			return nil, err
+		}
+		matched := selectContexts(manager, s, false)
+		if len(matched) == 0 {
+			return nil, fmt.Errorf("no contexts match selector '%s'", s)
+		}
+		for _, name := range matched {
+			add(name)
+		}
+	}
+	return names, nil
+}
+
+// selectContexts returns the sorted contexts whose labels match s. If
+// managedOnly is set, only the synced context of each cluster managed by
+// nks-ctx is considered, not its derived contexts.
+func selectContexts(manager *kubeconfig.Manager, s selector.Selector, managedOnly bool) []string {
+	var names []string
+	for _, name := range manager.Select(s, clusterStatuses()) {
+		if meta := manager.Meta(name); managedOnly && (meta == nil || meta.Derived) {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// clusterStatuses returns a StatusFunc that reads cluster statuses from the
+// NKS API, listing the clusters of each profile the first time it is asked
+// about. Profiles that fail are reported once and yield "".
+func clusterStatuses() kubeconfig.StatusFunc {
+	byProfile := make(map[string]map[string]string)
+	return func(profile, clusterUUID string) string {
+		statuses, ok := byProfile[profile]
+		if !ok {
+			statuses = make(map[string]string)
+			byProfile[profile] = statuses
+			clusters, err := listProfileClusters(profile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "  Warning: cannot read cluster status for profile %s: %v\n", profile, oneLine(err.Error()))
+			}
+			for _, c := range clusters {
+				statuses[c.UUID] = c.Status
+			}
+		}
+		return statuses[clusterUUID]
+	}
+}
+
+// writePrivateFile replaces path with data through a 0600 temp file in the
+// same directory, so the credentials are never readable by others, not even
+// while an existing file is being overwritten.
+func writePrivateFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".nks-ctx-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func listProfileClusters(profile string) ([]ncp.Cluster, error) {
+	cfg, err := ncp.LoadConfig(profile)
+	if err != nil {
+		return nil, err
+	}
+	return ncp.NewClientFromConfig(cfg).ListClusters()
+}
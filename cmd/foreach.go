@@ -0,0 +1,270 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/consol-lee/nks-ctx/pkg/kubeconfig"
+	"github.com/consol-lee/nks-ctx/pkg/selector"
+)
+
+var (
+	foreachSelector string
+	foreachAll      bool
+	foreachParallel int
+	foreachFailFast bool
+)
+
+var foreachCmd = &cobra.Command{
+	Use:   "foreach (--selector <expr> | --all) -- <command> [args...]",
+	Short: "Run a command against every selected cluster",
+	Long: `Run a command once per synced cluster, each with its own temporary
+kubeconfig as in 'kubectl nks-ctx exec'. Clusters are selected from the
+contexts nks-ctx manages by name, context, region, profile and status
+(status is read from the NKS API):
+
+  kubectl nks-ctx foreach --selector 'region=KR,name=~prod-.*' -- kubectl get nodes
+  kubectl nks-ctx foreach --selector 'status=RUNNING' --parallel 8 -- helm list -A
+
+Output lines are prefixed with the cluster name. Up to --parallel clusters
+run at once; --fail-fast stops starting new runs and terminates running
+ones after the first failure. A summary table is printed to stderr, and the
+command exits 0 if every run succeeded, otherwise with the highest exit
+status (1 if a run could not start or was stopped).`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if cmd.ArgsLenAtDash() != 0 || len(args) == 0 {
+			return fmt.Errorf("usage: kubectl nks-ctx foreach (--selector <expr> | --all) -- <command> [args...]")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if (foreachSelector == "") == !foreachAll {
+			return fmt.Errorf("specify either --selector or --all")
+		}
+		if foreachParallel < 1 {
+			return fmt.Errorf("--parallel must be at least 1")
+		}
+		s, err := selector.Parse(foreachSelector)
+		if err != nil {
+			return err
+		}
+		path, err := exec.LookPath(args[0])
+		if err != nil {
+			return err
+		}
+
+		manager, err := newMergedManager()
+		if err != nil {
+			return fmt.Errorf("failed to read kubeconfig: %w", err)
+		}
+		names := selectContexts(manager, s, true)
+		if len(names) == 0 {
+			return fmt.Errorf("no synced clusters match selector '%s'", s)
+		}
+
+		results := runForeach(cmd.Context(), manager, names, path, args)
+		printForeachSummary(os.Stderr, results)
+		return foreachExit(results)
+	},
+}
+
+func init() {
+	foreachCmd.Flags().StringVarP(&foreachSelector, "selector", "l", "", "Clusters to run on, e.g. 'region=KR,name=~prod-.*'")
+	foreachCmd.Flags().BoolVar(&foreachAll, "all", false, "Run on every synced cluster")
+	foreachCmd.Flags().IntVarP(&foreachParallel, "parallel", "P", 4, "Maximum number of clusters to run on at once")
+	foreachCmd.Flags().BoolVar(&foreachFailFast, "fail-fast", false, "Stop after the first failure")
+	rootCmd.AddCommand(foreachCmd)
+}
+
+// Results of a foreach run on one cluster.
+const (
+	foreachOK        = "ok"
+	foreachFailed    = "failed"
+	foreachError     = "error"
+	foreachCancelled = "cancelled"
+	foreachSkipped   = "skipped"
+)
+
+type foreachResult struct {
+	Cluster  string
+	Context  string
+	Result   string
+	ExitCode int
+	Duration time.Duration
+	Err      error
+}
+
+// runForeach runs the command for each context with at most --parallel runs
+// at a time. Results are returned in the order of names.
+func runForeach(ctx context.Context, manager *kubeconfig.Manager, names []string, path string, args []string) []foreachResult {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	width := 0
+	for _, name := range names {
+		if n := len(manager.Labels(name)["name"]); n > width {
+			width = n
+		}
+	}
+
+	var outMu sync.Mutex
+	results := make([]foreachResult, len(names))
+	sem := make(chan struct{}, foreachParallel)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		results[i] = foreachResult{Cluster: manager.Labels(name)["name"], Context: name, Result: foreachSkipped}
+
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			<-sem
+			continue
+		}
+		wg.Add(1)
+		go func(r *foreachResult) {
+			defer func() { <-sem; wg.Done() }()
+
+			prefix := fmt.Sprintf("[%-*s] ", width, r.Cluster)
+			stdout := &prefixWriter{mu: &outMu, out: os.Stdout, prefix: prefix}
+			stderr := &prefixWriter{mu: &outMu, out: os.Stderr, prefix: prefix}
+			runForeachOne(ctx, manager, r, path, args, stdout, stderr)
+			stdout.Flush()
+			stderr.Flush()
+
+			if r.Result != foreachOK && foreachFailFast {
+				cancel()
+			}
+		}(&results[i])
+	}
+	wg.Wait()
+	return results
+}
+
+// runForeachOne runs the command for r.Context and records the outcome in r.
+// The command runs in its own process group, which is terminated if ctx ends.
+func runForeachOne(ctx context.Context, manager *kubeconfig.Manager, r *foreachResult, path string, args []string, stdout, stderr io.Writer) {
+	start := time.Now()
+	defer func() { r.Duration = time.Since(start) }()
+
+	kc, err := writeTempKubeconfig(manager, r.Context)
+	if err != nil {
+		r.Result, r.Err = foreachError, err
+		fmt.Fprintf(stderr, "%v\n", err)
+		return
+	}
+	defer kc.remove()
+
+	c := exec.CommandContext(ctx, path, args[1:]...)
+	c.Args[0] = args[0]
+	c.Env = kc.environ()
+	c.Stdout, c.Stderr = stdout, stderr
+	c.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	c.Cancel = func() error {
+		return syscall.Kill(-c.Process.Pid, syscall.SIGTERM)
+	}
+	c.WaitDelay = 5 * time.Second
+
+	err = c.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		r.Result = foreachOK
+	case ctx.Err() != nil:
+		r.Result, r.Err = foreachCancelled, ctx.Err()
+	case errors.As(err, &exitErr):
+		r.Result, r.ExitCode = foreachFailed, exitErr.ExitCode()
+	default:
+		r.Result, r.Err = foreachError, err
+		fmt.Fprintf(stderr, "%v\n", err)
+	}
+}
+
+// printForeachSummary writes one row per cluster and the totals.
+func printForeachSummary(w io.Writer, results []foreachResult) {
+	counts := make(map[string]int)
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CLUSTER\tCONTEXT\tRESULT\tEXIT\tDURATION")
+	for _, r := range results {
+		exit := "-"
+		if r.Result == foreachOK || r.Result == foreachFailed {
+			exit = fmt.Sprint(r.ExitCode)
+		}
+		duration := "-"
+		if r.Result != foreachSkipped {
+			duration = r.Duration.Round(10 * time.Millisecond).String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.Cluster, r.Context, r.Result, exit, duration)
+		counts[r.Result]++
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "%d ok, %d failed, %d error(s), %d cancelled, %d skipped\n",
+		counts[foreachOK], counts[foreachFailed], counts[foreachError], counts[foreachCancelled], counts[foreachSkipped])
+}
+
+// foreachExit returns nil if every run succeeded, otherwise an *ExitError
+// with the highest exit status, or 1 if only starts or cancellations failed.
+func foreachExit(results []foreachResult) error {
+	code := 0
+	for _, r := range results {
+		switch r.Result {
+		case foreachFailed:
+			if r.ExitCode > code {
+				code = r.ExitCode
+			}
+		case foreachError, foreachCancelled, foreachSkipped:
+			if code == 0 {
+				code = 1
+			}
+		}
+	}
+	if code == 0 {
+		return nil
+	}
+	return &ExitError{Code: code}
+}
+
+// prefixWriter writes complete lines to out with prefix, holding mu so lines
+// of concurrent runs do not interleave. Call Flush for a final partial line.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			return len(b), nil
+		}
+		p.writeLine(p.buf[:i+1])
+		p.buf = p.buf[i+1:]
+	}
+}
+
+// Flush writes a remaining partial line, terminated with a newline.
+func (p *prefixWriter) Flush() {
+	if len(p.buf) > 0 {
+		p.writeLine(append(p.buf, '\n'))
+		p.buf = nil
+	}
+}
+
+func (p *prefixWriter) writeLine(line []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	io.WriteString(p.out, p.prefix)
+	p.out.Write(line)
+}
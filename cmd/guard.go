@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/consol-lee/nks-ctx/pkg/config"
+	"github.com/consol-lee/nks-ctx/pkg/kubeconfig"
+	"github.com/consol-lee/nks-ctx/pkg/state"
+	"golang.org/x/term"
+)
+
+var yesFlag bool
+
+// revertCmd is started detached by a switch that schedules a revert, so the
+// revert happens even if the terminal is closed. Any later invocation also
+// applies reverts that are due.
+var revertCmd = &cobra.Command{
+	Use:    "revert-pending",
+	Short:  "Wait for pending context reverts and apply them",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		for {
+			s, err := state.Load(state.DefaultPath())
+			if err != nil {
+				return err
+			}
+			next, ok := s.NextRevert()
+			if !ok {
+				return nil
+			}
+			if wait := time.Until(next); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-cmd.Context().Done():
+					return nil
+				}
+				// Re-read: the revert may have been replaced or cancelled.
+				continue
+			}
+			if err := applyDueReverts(); err != nil {
+				return err
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVarP(&yesFlag, "yes", "y", false, "Switch to protected clusters without confirmation")
+	rootCmd.AddCommand(revertCmd)
+}
+
+// guardFor returns the guard protecting a context, or nil. Read-only
+// contexts are not guarded.
+func guardFor(manager *kubeconfig.Manager, contextName string) *config.Guard {
+	if manager.IsReadOnly(contextName) {
+		return nil
+	}
+	labels := manager.Labels(contextName)
+	return settings.Guard(labels["name"], contextName, labels["profile"], labels["region"])
+}
+
+// confirmGuard asks the user to type the cluster name before switching to a
+// guarded context. Without a terminal, --yes is required instead.
+func confirmGuard(manager *kubeconfig.Manager, g *config.Guard, contextName string) error {
+	if yesFlag {
+		return nil
+	}
+	name := manager.Labels(contextName)["name"]
+	if name == "" {
+		name = contextName
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("'%s' is a protected cluster (guard %s); pass --yes to switch to it non-interactively", name, g)
+	}
+
+	fmt.Fprintln(os.Stderr, paint(os.Stderr, "red", fmt.Sprintf("'%s' is a protected cluster (guard %s).", name, g)))
+	fmt.Fprintf(os.Stderr, "Type the cluster name to switch: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return fmt.Errorf("confirmation aborted; context not switched")
+	}
+	if strings.TrimSpace(line) != name {
+		return fmt.Errorf("'%s' does not match '%s'; context not switched", strings.TrimSpace(line), name)
+	}
+	return nil
+}
+
+// startRevertHelper starts revert-pending in its own session, detached from
+// the terminal.
+func startRevertHelper() error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	c := exec.Command(self, "revert-pending")
+	c.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Process.Release()
+}
+
+// applyDueReverts switches back every kubeconfig whose pending revert is due
+// and whose current-context has not been changed since.
+func applyDueReverts() error {
+	path := state.DefaultPath()
+	s, err := state.Load(path)
+	if err != nil {
+		return err
+	}
+	if len(s.DueReverts(time.Now())) == 0 {
+		return nil
+	}
+
+	// Re-read under the lock: another process may have applied them.
+	return state.Update(path, func(s *state.State) error {
+		now := time.Now()
+		for _, r := range s.DueReverts(now) {
+			s.ClearRevert(r.Kubeconfig)
+			manager, err := kubeconfig.NewManagerForPath(r.Kubeconfig)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "  Warning: cannot revert %s: %v\n", r.Kubeconfig, err)
+				continue
+			}
+			if manager.GetCurrentContext() != r.Context {
+				continue
+			}
+			manager.SetCurrentContext(r.To)
+			if err := manager.Save(); err != nil {
+				fmt.Fprintf(os.Stderr, "  Warning: cannot revert %s: %v\n", r.Kubeconfig, err)
+				continue
+			}
+			s.RecordSwitch(r.Kubeconfig, r.Context, r.To, now)
+			fmt.Fprintf(os.Stderr, "Switched back from \"%s\" to \"%s\" (time limit reached)\n", r.Context, r.To)
+		}
+		return nil
+	})
+}
+
+// revertDue applies reverts that are due, warning instead of failing. The
+// commands that show or change the current context call it first; token
+// and completion must not, since kubectl runs them while it is using the
+// kubeconfig, and prompt must stay fast.
+func revertDue() {
+	if err := applyDueReverts(); err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: cannot apply pending context reverts: %v\n", err)
+	}
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/consol-lee/nks-ctx/pkg/state"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history [index]",
+	Short: "List recent context switches, or switch back to one by index",
+	Long: `List the contexts nks-ctx switched to in the current kubeconfig, most recent
+first, with when each switch happened. Pass an index from the list to switch
+back to that context, with --for to switch back again after a while:
+
+  kubectl nks-ctx history
+  kubectl nks-ctx history 3
+  kubectl nks-ctx history 3 --for 30m
+
+'kubectl nks-ctx -' switches to the previous context, like 'kubectx -'.
+Switches are recorded in ~/.local/state/nks-ctx/state.json.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		revertDue()
+		s, err := state.Load(state.DefaultPath())
+		if err != nil {
+			return err
+		}
+		manager, err := newMergedManager()
+		if err != nil {
+			return fmt.Errorf("failed to read kubeconfig: %w", err)
+		}
+		recent := s.Recent(manager.Path())
+
+		if forFlag < 0 {
+			return fmt.Errorf("--for must be positive")
+		}
+		if forFlag != 0 && len(args) == 0 {
+			return fmt.Errorf("--for needs a history index")
+		}
+		if len(args) == 1 {
+			i, err := strconv.Atoi(args[0])
+			if err != nil || i < 1 || i > len(recent) {
+				return fmt.Errorf("invalid history index %q: want 1-%d", args[0], len(recent))
+			}
+			return switchTo(manager, recent[i-1].Context, forFlag)
+		}
+
+		if outputFlag == "json" {
+			if recent == nil {
+				recent = []state.Switch{}
+			}
+			return encodeJSON(recent)
+		}
+		if len(recent) == 0 {
+			fmt.Println("No context switches recorded yet.")
+			return nil
+		}
+		current := manager.GetCurrentContext()
+		now := time.Now()
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  #\tTIME\tCONTEXT")
+		for i, sw := range recent {
+			marker := " "
+			if i == 0 && sw.Context == current {
+				marker = "*"
+			}
+			fmt.Fprintf(tw, "%s %d\t%s (%s ago)\t%s\n", marker, i+1, sw.Time.Local().Format("2006-01-02 15:04:05"), now.Sub(sw.Time).Round(time.Second), sw.Context)
+		}
+		return tw.Flush()
+	},
+}
+
+func init() {
+	historyCmd.Flags().StringVarP(&outputFlag, "output", "o", "", "Output format: text or json")
+	historyCmd.Flags().DurationVar(&forFlag, "for", 0, "Switch back to the current context after this long, e.g. 30m")
+	rootCmd.AddCommand(historyCmd)
+}
+
+// runSwitchBack switches to the previous context, like `kubectx -`, and back
+// again after revertAfter if it is set.
+func runSwitchBack(revertAfter time.Duration) error {
+	s, err := state.Load(state.DefaultPath())
+	if err != nil {
+		return err
+	}
+	manager, err := newMergedManager()
+	if err != nil {
+		return fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+	previous, ok := s.Previous(manager.Path(), manager.GetCurrentContext())
+	if !ok {
+		return fmt.Errorf("no previous context recorded; switch with 'kubectl nks-ctx <cluster>' first")
+	}
+	return switchTo(manager, previous, revertAfter)
+}
+
+// recordSwitch adds a switch to the history and replaces the pending revert
+// of the kubeconfig: none, or a switch back to from at revertAt if set.
+// Failing to record does not fail the switch.
+func recordSwitch(kubeconfigPath, from, to string, revertAt time.Time) {
+	err := state.Update(state.DefaultPath(), func(s *state.State) error {
+		s.RecordSwitch(kubeconfigPath, from, to, time.Now())
+		s.ClearRevert(kubeconfigPath)
+		if !revertAt.IsZero() {
+			s.SetRevert(state.Revert{Kubeconfig: kubeconfigPath, Context: to, To: from, At: revertAt})
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: cannot record context switch: %v\n", err)
+	}
+}
+
+// extendRevert moves the pending switch back from contextName, the current
+// context of kubeconfigPath, to at. It returns the context switched back to.
+func extendRevert(kubeconfigPath, contextName string, at time.Time) (string, error) {
+	var to string
+	err := state.Update(state.DefaultPath(), func(s *state.State) error {
+		r, ok := s.PendingRevert(kubeconfigPath)
+		if !ok || r.Context != contextName {
+			return fmt.Errorf("already on context '%s' with no switch back pending; --for needs a previous context", contextName)
+		}
+		r.At = at
+		s.SetRevert(r)
+		to = r.To
+		return nil
+	})
+	return to, err
+}
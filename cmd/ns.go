@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/consol-lee/nks-ctx/pkg/kubeconfig"
+	"github.com/consol-lee/nks-ctx/pkg/state"
+)
+
+var namespaceFlag string
+
+var nsCmd = &cobra.Command{
+	Use:   "ns [namespace]",
+	Short: "Show or change the namespace of the current context",
+	Long: `Without arguments, list the namespaces of the current context's cluster
+and mark the active one. With a namespace, set it on the current context in
+kubeconfig, like kubens. The namespace is remembered for the cluster and
+restored the next time you switch to it:
+
+  kubectl nks-ctx ns payments
+  kubectl nks-ctx my-cluster -n payments`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeNamespaces,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		revertDue()
+		manager, err := newMergedManager()
+		if err != nil {
+			return fmt.Errorf("failed to read kubeconfig: %w", err)
+		}
+		contextName := manager.GetCurrentContext()
+		if contextName == "" {
+			return fmt.Errorf("no current context; switch with 'kubectl nks-ctx <cluster>' first")
+		}
+
+		if len(args) == 1 {
+			if err := useNamespace(cmd.Context(), manager, contextName, args[0]); err != nil {
+				return err
+			}
+			fmt.Printf("Active namespace is \"%s\"\n", args[0])
+			return nil
+		}
+
+		names, err := listNamespaces(cmd.Context(), manager, contextName, 10*time.Second)
+		if err != nil {
+			return fmt.Errorf("failed to list namespaces: %w", err)
+		}
+		current := manager.Namespace(contextName)
+		if current == "" {
+			current = "default"
+		}
+		for _, name := range names {
+			marker := "  "
+			if name == current {
+				marker = "* "
+			}
+			fmt.Printf("%s%s\n", marker, name)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.Flags().StringVarP(&namespaceFlag, "namespace", "n", "", "Namespace to use in the cluster switched to")
+	rootCmd.RegisterFlagCompletionFunc("namespace", completeNamespaces)
+	rootCmd.AddCommand(nsCmd)
+}
+
+// useNamespace checks a namespace against the cluster, then sets it on a
+// context and remembers it for the cluster.
+func useNamespace(ctx context.Context, manager *kubeconfig.Manager, contextName, namespace string) error {
+	if err := checkNamespace(ctx, manager, contextName, namespace); err != nil {
+		return err
+	}
+	return setNamespace(manager, contextName, namespace)
+}
+
+// setNamespace sets the namespace of a context without checking it, and
+// remembers it for the cluster.
+func setNamespace(manager *kubeconfig.Manager, contextName, namespace string) error {
+	if err := manager.SetNamespace(contextName, namespace); err != nil {
+		return fmt.Errorf("failed to set namespace: %w", err)
+	}
+	rememberNamespace(manager, contextName, namespace)
+	return nil
+}
+
+// checkNamespace returns an error if the context's cluster has no namespace
+// of that name. If namespaces cannot be listed it only warns.
+func checkNamespace(ctx context.Context, manager *kubeconfig.Manager, contextName, namespace string) error {
+	names, err := listNamespaces(ctx, manager, contextName, 10*time.Second)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: cannot verify namespace '%s': %v\n", namespace, err)
+		return nil
+	}
+	if !slices.Contains(names, namespace) {
+		return fmt.Errorf("namespace '%s' not found in %s", namespace, contextName)
+	}
+	return nil
+}
+
+// restoreNamespace sets the namespace last used in the context's cluster if
+// the context has none, without contacting the cluster. It returns the
+// namespace restored, or "".
+func restoreNamespace(manager *kubeconfig.Manager, contextName string) string {
+	if manager.Namespace(contextName) != "" {
+		return ""
+	}
+	s, err := state.Load(state.DefaultPath())
+	if err != nil {
+		return ""
+	}
+	namespace := s.LastNamespace(clusterKey(manager, contextName))
+	if namespace == "" {
+		return ""
+	}
+	if err := manager.SetNamespace(contextName, namespace); err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: cannot restore namespace '%s': %v\n", namespace, err)
+		return ""
+	}
+	return namespace
+}
+
+// rememberNamespace records the namespace used in the context's cluster.
+// Failing to record does not fail the command.
+func rememberNamespace(manager *kubeconfig.Manager, contextName, namespace string) {
+	err := state.Update(state.DefaultPath(), func(s *state.State) error {
+		s.RememberNamespace(clusterKey(manager, contextName), namespace)
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: cannot remember namespace: %v\n", err)
+	}
+}
+
+// clusterKey identifies the cluster of a context across renames: its NKS UUID
+// if nks-ctx manages it, otherwise its API server URL or the context name.
+func clusterKey(manager *kubeconfig.Manager, contextName string) string {
+	if meta := manager.Meta(contextName); meta != nil && meta.ClusterUUID != "" {
+		return meta.ClusterUUID
+	}
+	if server := manager.Server(contextName); server != "" {
+		return server
+	}
+	return contextName
+}
+
+// listNamespaces lists the namespaces of a context's cluster, reusing a list
+// fetched within kubeconfig.NamespaceCacheTTL.
+func listNamespaces(ctx context.Context, manager *kubeconfig.Manager, contextName string, timeout time.Duration) ([]string, error) {
+	cache := kubeconfig.LoadNamespaceCache(kubeconfig.DefaultNamespaceCachePath())
+	key := clusterKey(manager, contextName)
+	if names, ok := cache.Get(key, kubeconfig.NamespaceCacheTTL); ok {
+		return names, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	names, err := manager.ListNamespaces(ctx, contextName)
+	if err != nil {
+		return nil, err
+	}
+	cache.Put(key, names)
+	return names, nil
+}
+
+// completeNamespaces completes namespaces of the cluster named in args, or of
+// the current context for the ns command.
+func completeNamespaces(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if cmd.Name() == "ns" && len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	if err := initSettings(); err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	manager, err := newMergedManager()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	contextName := manager.GetCurrentContext()
+	if cmd.Name() != "ns" && len(args) > 0 {
+		if contextName, err = manager.FindContext(args[0]); err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+	}
+	if contextName == "" {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	names, err := listNamespaces(ctx, manager, contextName, 3*time.Second)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var matches []string
+	for _, name := range names {
+		if strings.HasPrefix(name, toComplete) {
+			matches = append(matches, name)
+		}
+	}
+	return matches, cobra.ShellCompDirectiveNoFileComp
+}
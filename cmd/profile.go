@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/consol-lee/nks-ctx/pkg/ncp"
+	"github.com/consol-lee/nks-ctx/pkg/vault"
+)
+
+var (
+	profileAccessKey string
+	profileSecretKey string
+	profileApiURL    string
+	profileRegion    string
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage NCP profiles in ~/.ncloud/configure",
+	Long: `Manage NCP profiles in ~/.ncloud/configure.
+
+Edits preserve comments and ordering of the file and always leave it
+with 0600 permissions.`,
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List profiles",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := ncp.OpenConfigFile(ncp.DefaultConfigPath())
+		if err != nil {
+			return err
+		}
+		v, err := vault.Open(vault.DefaultPath())
+		if err != nil {
+			return err
+		}
+
+		active := profileFlag
+		if active == "" {
+			active = "DEFAULT"
+		}
+
+		names := file.Profiles()
+		for _, name := range v.Profiles() {
+			if !file.HasProfile(name) {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			fmt.Printf("No profiles in %s\n", file.Path())
+			return nil
+		}
+
+		for _, name := range names {
+			marker := "  "
+			if name == active {
+				marker = "* "
+			}
+			data, _ := file.Profile(name)
+			fmt.Printf("%s%-20s %s\n", marker, name, credentialKind(data, v.Has(name)))
+		}
+		return nil
+	},
+}
+
+var profileShowCmd = &cobra.Command{
+	Use:   "show [name]",
+	Short: "Show a profile with the secret key redacted",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := profileArg(args)
+		file, err := ncp.OpenConfigFile(ncp.DefaultConfigPath())
+		if err != nil {
+			return err
+		}
+		data, ok := file.Profile(name)
+		if !ok {
+			return fmt.Errorf("profile '%s' not found in %s", name, file.Path())
+		}
+
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		fmt.Printf("[%s]\n", name)
+		for _, k := range keys {
+			value := data[k]
+			if k == "ncloud_secret_access_key" {
+				value = ncp.RedactSecret(value)
+			}
+			fmt.Printf("%s=%s\n", k, value)
+		}
+		return nil
+	},
+}
+
+var profileAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add a profile",
+	Long: `Add a profile to ~/.ncloud/configure.
+
+The secret key is prompted for if --secret-key is not given, so it does not
+end up in shell history.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		file, err := ncp.OpenConfigFile(ncp.DefaultConfigPath())
+		if err != nil {
+			return err
+		}
+		if file.HasProfile(name) {
+			return fmt.Errorf("profile '%s' already exists in %s", name, file.Path())
+		}
+
+		if profileAccessKey == "" {
+			if profileAccessKey, err = vault.ReadLine("Access key: "); err != nil {
+				return err
+			}
+		}
+		if profileSecretKey == "" {
+			if profileSecretKey, err = vault.ReadSecret("Secret key: "); err != nil {
+				return err
+			}
+		}
+		if profileAccessKey == "" || profileSecretKey == "" {
+			return fmt.Errorf("access key and secret key are required")
+		}
+		if profileApiURL != "" {
+			if u, err := url.Parse(profileApiURL); err != nil || u.Scheme != "https" || u.Host == "" {
+				return fmt.Errorf("invalid --api-url %q: expected https://host", profileApiURL)
+			}
+		}
+
+		file.Set(name, "ncloud_access_key_id", profileAccessKey)
+		file.Set(name, "ncloud_secret_access_key", profileSecretKey)
+		if profileApiURL != "" {
+			file.Set(name, "ncloud_api_url", profileApiURL)
+		}
+		if profileRegion != "" {
+			file.Set(name, "ncloud_region", profileRegion)
+		}
+
+		if err := file.Save(); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.Path(), err)
+		}
+		fmt.Printf("Added profile '%s'\n", name)
+		return nil
+	},
+}
+
+var profileRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a profile",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		file, err := ncp.OpenConfigFile(ncp.DefaultConfigPath())
+		if err != nil {
+			return err
+		}
+		if !file.RemoveProfile(name) {
+			return fmt.Errorf("profile '%s' not found in %s", name, file.Path())
+		}
+		if err := file.Save(); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.Path(), err)
+		}
+		fmt.Printf("Removed profile '%s'\n", name)
+		return nil
+	},
+}
+
+var profileValidateCmd = &cobra.Command{
+	Use:   "validate [name]",
+	Short: "Check a profile and confirm its keys with a live signed API call",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := profileArg(args)
+
+		file, err := ncp.OpenConfigFile(ncp.DefaultConfigPath())
+		if err != nil {
+			return err
+		}
+		for _, w := range file.Warnings() {
+			fmt.Printf("  warning: %s\n", w)
+		}
+		if data, err := file.Resolve(name); err == nil {
+			if problems := profileProblems(data); len(problems) > 0 {
+				for _, p := range problems {
+					fmt.Printf("  ! %s\n", p)
+				}
+				return fmt.Errorf("profile '%s' in %s is invalid", name, file.Path())
+			}
+		}
+
+		// Environment variables would mask the profile under test.
+		cfg, err := ncp.DefaultChain().Without("env").Retrieve(name)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Credentials: %s (from %s)\n", cfg.AccessKey, cfg.Source)
+
+		if err := ncp.NewClientFromConfig(cfg).Validate(); err != nil {
+			return fmt.Errorf("profile '%s' rejected by %s: %w", name, cfg.ApiURL, err)
+		}
+		fmt.Printf("Profile '%s' is valid: signed request to %s succeeded\n", name, cfg.ApiURL)
+		return nil
+	},
+}
+
+func init() {
+	profileAddCmd.Flags().StringVar(&profileAccessKey, "access-key", "", "NCP access key ID")
+	profileAddCmd.Flags().StringVar(&profileSecretKey, "secret-key", "", "NCP secret key (prompted if omitted)")
+	profileAddCmd.Flags().StringVar(&profileApiURL, "api-url", "", "NCP API gateway URL (default: https://ncloud.apigw.ntruss.com)")
+	profileAddCmd.Flags().StringVar(&profileRegion, "region", "", "Default region code")
+
+	profileCmd.AddCommand(profileListCmd, profileShowCmd, profileAddCmd, profileRemoveCmd, profileValidateCmd)
+	rootCmd.AddCommand(profileCmd)
+}
+
+// profileArg returns the profile named on the command line, then --profile, then DEFAULT.
+func profileArg(args []string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	if profileFlag != "" {
+		return profileFlag
+	}
+	return "DEFAULT"
+}
+
+func credentialKind(data map[string]string, inVault bool) string {
+	switch {
+	case data["ncloud_access_key_id"] != "" && data["ncloud_secret_access_key"] != "":
+		return "plaintext"
+	case inVault:
+		return "vault"
+	case data["credential_process"] != "":
+		return "credential_process"
+	}
+	return "incomplete"
+}
+
+// profileProblems reports mistakes in a profile that would otherwise only
+// surface as "incomplete credentials".
+func profileProblems(data map[string]string) []string {
+	var problems []string
+	ak, sk := data["ncloud_access_key_id"], data["ncloud_secret_access_key"]
+	if ak != "" && sk == "" {
+		problems = append(problems, "ncloud_access_key_id is set but ncloud_secret_access_key is missing")
+	}
+	if sk != "" && ak == "" {
+		problems = append(problems, "ncloud_secret_access_key is set but ncloud_access_key_id is missing")
+	}
+	if raw := data["ncloud_api_url"]; raw != "" {
+		if u, err := url.Parse(raw); err != nil || u.Scheme != "https" || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("ncloud_api_url %q is not an https URL", raw))
+		}
+	}
+	return problems
+}
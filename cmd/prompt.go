@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+	"github.com/consol-lee/nks-ctx/pkg/kubeconfig"
+	"github.com/consol-lee/nks-ctx/pkg/ncp"
+)
+
+// defaultPromptFormat is used when neither --format nor prompt.format in the
+// config is set.
+const defaultPromptFormat = "{{.Name}}{{if .Namespace}}:{{.Namespace}}{{end}}"
+
+var (
+	formatFlag string
+	shellFlag  string
+
+	// promptSettingsErr is set if the plugin config cannot be loaded.
+	promptSettingsErr error
+)
+
+var promptCmd = &cobra.Command{
+	Use:   "prompt",
+	Short: "Print the current cluster for a shell prompt",
+	Long: `Print the current cluster for a shell prompt, rendered with a Go template:
+
+  kubectl nks-ctx prompt --format '{{.Profile}}:{{.Name}}({{.Region}})'
+
+Fields: .Context, .Name, .UUID, .Region, .Profile, .Namespace, .Status,
+.ReadOnly and .Protected. .Status is the cluster status as of the last sync.
+
+The prompt reads only the kubeconfig and the cluster list cached by sync; it
+never calls the NKS API or applies pending switches back. It prints nothing if
+there is no current context or the plugin config is invalid.
+The output is coloured by the first matching rule in prompt.colors of the
+config; pass --shell bash or --shell zsh to mark the colour codes so the
+shell does not count them as printed. NO_COLOR disables colours.`,
+	Args: cobra.NoArgs,
+	// A prompt must not fail the shell on every render, so a broken config
+	// prints nothing instead of an error.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		promptSettingsErr = initSettings()
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if promptSettingsErr != nil {
+			return nil
+		}
+		format := formatFlag
+		if format == "" {
+			format = settings.Prompt.Format
+		}
+		if format == "" {
+			format = defaultPromptFormat
+		}
+		tmpl, err := template.New("prompt").Parse(format)
+		if err != nil {
+			return fmt.Errorf("invalid --format: %w", err)
+		}
+		var open, close string
+		switch shellFlag {
+		case "":
+		case "bash":
+			open, close = `\[`, `\]`
+		case "zsh":
+			open, close = "%{", "%}"
+		default:
+			return fmt.Errorf("invalid --shell %q: use bash or zsh", shellFlag)
+		}
+
+		// No kubeconfig prints nothing.
+		manager, err := promptManager()
+		if err != nil {
+			return nil
+		}
+		current := manager.GetCurrentContext()
+		if current == "" {
+			return nil
+		}
+		labels := manager.Labels(current)
+		fields := promptFields{
+			Context:   current,
+			Name:      labels["name"],
+			Region:    labels["region"],
+			Profile:   labels["profile"],
+			Namespace: manager.Namespace(current),
+			ReadOnly:  manager.IsReadOnly(current),
+			Protected: guardFor(manager, current) != nil,
+		}
+		if meta := manager.Meta(current); meta != nil {
+			fields.UUID = meta.ClusterUUID
+			if e, ok := ncp.LoadInventory(ncp.DefaultInventoryPath()).Get(meta.ClusterUUID); ok {
+				fields.Status = e.Status
+			}
+		}
+
+		var b strings.Builder
+		if err := tmpl.Execute(&b, fields); err != nil {
+			return fmt.Errorf("invalid --format: %w", err)
+		}
+		out := b.String()
+		if color := settings.PromptColor(fields.Name, current, fields.Profile, fields.Region); color != "" && os.Getenv("NO_COLOR") == "" {
+			out = colorize(color, out, open, close)
+		}
+		fmt.Println(out)
+		return nil
+	},
+}
+
+// promptManager loads the kubeconfig files that describe the current context:
+// in the files layout, only its per-cluster file rather than all of them.
+func promptManager() (*kubeconfig.Manager, error) {
+	if !filesLayout() {
+		return newMergedManager()
+	}
+	primary, err := kubeconfig.NewManagerForPath(kubeconfigPath())
+	if err != nil {
+		return nil, err
+	}
+	current := primary.GetCurrentContext()
+	return kubeconfig.NewManagerForPaths(kubeconfigPathsFor(func(e kubeconfig.IndexEntry) bool {
+		return e.Context == current
+	}))
+}
+
+type promptFields struct {
+	Context   string
+	Name      string
+	UUID      string
+	Region    string
+	Profile   string
+	Namespace string
+	Status    string
+	ReadOnly  bool
+	Protected bool
+}
+
+func init() {
+	promptCmd.Flags().StringVar(&formatFlag, "format", "", "Go template for the prompt (default: prompt.format from the config, or "+defaultPromptFormat+")")
+	promptCmd.Flags().StringVar(&shellFlag, "shell", "", "Mark colour codes for the shell: bash or zsh")
+	rootCmd.AddCommand(promptCmd)
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/consol-lee/nks-ctx/pkg/config"
+	"github.com/consol-lee/nks-ctx/pkg/kubeconfig"
+)
+
+var readOnlyFlag bool
+
+func init() {
+	rootCmd.Flags().BoolVar(&readOnlyFlag, "read-only", false, "Switch to the cluster's read-only context, creating it if needed")
+}
+
+// readOnlyIdentity returns the user and groups read-only contexts impersonate.
+func readOnlyIdentity() (string, []string) {
+	user, group := settings.ReadOnlyUser, settings.ReadOnlyGroup
+	if user == "" {
+		user = config.DefaultReadOnlyUser
+	}
+	if group == "" {
+		group = config.DefaultReadOnlyGroup
+	}
+	return user, []string{group}
+}
+
+// readOnlyContext returns the read-only context of contextName's cluster,
+// creating it next to the synced context if it does not exist yet. It reports
+// whether the context was created, in which case manager is out of date.
+func readOnlyContext(ctx context.Context, manager *kubeconfig.Manager, contextName string) (string, bool, error) {
+	if manager.IsReadOnly(contextName) {
+		return contextName, false, nil
+	}
+	meta := manager.Meta(contextName)
+	if meta == nil {
+		return "", false, fmt.Errorf("context '%s' is not managed by nks-ctx; run 'kubectl nks-ctx' to sync it", contextName)
+	}
+	name := kubeconfig.ReadOnlyName(meta.ClusterName)
+	if manager.IsReadOnly(name) {
+		return name, false, nil
+	}
+
+	parent := contextName
+	if meta.Derived {
+		parents := manager.ContextsForCluster(meta.ClusterUUID)
+		if len(parents) == 0 {
+			return "", false, fmt.Errorf("synced context of cluster '%s' not found; run 'kubectl nks-ctx' to sync it", meta.ClusterName)
+		}
+		parent = parents[0]
+	}
+
+	user, groups := readOnlyIdentity()
+	checkCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	var denied *kubeconfig.AccessDeniedError
+	if err := manager.CheckImpersonation(checkCtx, parent, user, groups); errors.As(err, &denied) {
+		return "", false, fmt.Errorf(
+			"cannot create a read-only context for '%s': %v.\n"+
+				"Ask a cluster admin to allow impersonating user %s and group %s, or set readOnlyUser/readOnlyGroup in the plugin config.",
+			meta.ClusterName, err, user, groups[0],
+		)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: cannot verify that impersonation is allowed: %v\n", err)
+	}
+
+	path := manager.Origin(parent)
+	owner, err := kubeconfig.NewManagerForPath(path)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+	if err := owner.CreateReadOnly(parent, name, user, groups); err != nil {
+		return "", false, err
+	}
+	if err := owner.Save(); err != nil {
+		return "", false, fmt.Errorf("failed to update kubeconfig: %w", err)
+	}
+	fmt.Printf("Created read-only context \"%s\" in %s\n", name, path)
+	return name, true, nil
+}
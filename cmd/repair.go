@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/consol-lee/nks-ctx/pkg/kubeconfig"
+	"github.com/consol-lee/nks-ctx/pkg/ncp"
+	"github.com/consol-lee/nks-ctx/pkg/vault"
+)
+
+var repairDryRun bool
+
+var repairCmd = &cobra.Command{
+	Use:   "repair",
+	Short: "Fix stale authenticator paths and args in managed kubeconfig users",
+	Long: `Scan the exec users of contexts managed by nks-ctx and rewrite:
+
+  - commands that no longer exist (e.g. /usr/local/bin/ncp-iam-authenticator
+    after a Homebrew upgrade or a move to Apple Silicon), using the
+    authenticator nks-ctx finds now
+  - missing --clusterUuid / --region args, from the context's metadata
+  - --profile values that no longer exist, if the context's recorded
+    profile still does
+
+A unified diff of the changes is printed before kubeconfig is written.
+Use --dry-run to only preview it.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		manager, err := newManager()
+		if err != nil {
+			return fmt.Errorf("failed to read kubeconfig: %w", err)
+		}
+
+		before, err := manager.Marshal()
+		if err != nil {
+			return err
+		}
+
+		opts := kubeconfig.RepairOptions{
+			ProfileExists: profileExists,
+		}
+		if authenticator := newAuthenticator(profileFlag); authenticator.IsInstalled() {
+			opts.AuthenticatorPath = authenticator.Path()
+		}
+		if exe, err := os.Executable(); err == nil {
+			opts.TokenCommand = exe
+		}
+
+		repairs := manager.RepairExecUsers(opts)
+		if len(repairs) == 0 {
+			fmt.Printf("All %d managed context(s) in %s look healthy.\n", len(manager.ManagedContexts()), manager.Path())
+			return nil
+		}
+
+		after, err := manager.Marshal()
+		if err != nil {
+			return err
+		}
+		if diff := manager.Diff(before, after); diff != "" {
+			fmt.Println(diff)
+		}
+
+		fixed, unfixed := 0, 0
+		for _, r := range repairs {
+			fmt.Printf("%s (user %s)\n", r.Context, r.User)
+			for _, f := range r.Fixed {
+				fmt.Printf("  fixed: %s\n", f)
+			}
+			for _, u := range r.Unfixed {
+				fmt.Printf("  ! %s\n", u)
+			}
+			fixed += len(r.Fixed)
+			unfixed += len(r.Unfixed)
+		}
+
+		switch {
+		case fixed == 0:
+		case repairDryRun:
+			fmt.Printf("\nDry run: %d fix(es) not written to %s\n", fixed, manager.Path())
+		default:
+			if err := manager.Save(); err != nil {
+				return fmt.Errorf("failed to write kubeconfig: %w", err)
+			}
+			fmt.Printf("\nApplied %d fix(es) to %s\n", fixed, manager.Path())
+		}
+
+		if unfixed > 0 {
+			return fmt.Errorf("%d problem(s) need manual action", unfixed)
+		}
+		return nil
+	},
+}
+
+func init() {
+	repairCmd.Flags().BoolVar(&repairDryRun, "dry-run", false, "Print the diff without writing kubeconfig")
+	rootCmd.AddCommand(repairCmd)
+}
+
+// profileExists reports whether a profile can still be resolved from
+// ~/.ncloud/configure or the vault. DEFAULT is always accepted, since its
+// credentials may come from the environment.
+func profileExists(name string) bool {
+	if name == "" || name == "DEFAULT" {
+		return true
+	}
+	if file, err := ncp.OpenConfigFile(ncp.DefaultConfigPath()); err == nil && file.HasProfile(name) {
+		return true
+	}
+	if v, err := vault.Open(vault.DefaultPath()); err == nil && v.Has(name) {
+		return true
+	}
+	return false
+}
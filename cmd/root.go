@@ -1,16 +1,28 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"sort"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/consol-lee/nks-ctx/pkg/kubeconfig"
 	"github.com/consol-lee/nks-ctx/pkg/ncp"
 )
 
-var profileFlag string
+var (
+	profileFlag           string
+	outputFlag            string
+	authenticatorPathFlag string
+	forFlag               time.Duration
+)
 
 var rootCmd = &cobra.Command{
 	Use:   "kubectl-nks-ctx [cluster-name]",
@@ -27,128 +39,203 @@ Examples:
   # Switch to a specific cluster
   kubectl nks-ctx my-cluster
 
-  # Use a specific NCP profile
-  kubectl nks-ctx --profile finance`,
+  # Switch and set the namespace
+  kubectl nks-ctx my-cluster -n payments
+
+  # Switch to a read-only context that impersonates a view-only group
+  kubectl nks-ctx my-cluster --read-only
+
+  # Switch to a protected cluster from a script
+  kubectl nks-ctx prod-kr --yes
+
+  # Switch for 30 minutes, then back to the current context
+  kubectl nks-ctx prod-kr --for 30m
+
+  # Switch back to the previous context
+  kubectl nks-ctx -
+
+  # Use a specific NCP profile (or set NCLOUD_PROFILE)
+  kubectl nks-ctx --profile finance
+
+Defaults for profile, regions, context naming, kubeconfig path and output
+format are read from ~/.config/nks-ctx/config.yaml and overridden by the
+nearest .nks-ctx.yaml in the current directory or its parents.`,
 	Args:          cobra.MaximumNArgs(1),
 	RunE:          run,
 	SilenceUsage:  true,
 	SilenceErrors: true,
-	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		if len(args) > 0 {
-			return nil, cobra.ShellCompDirectiveNoFileComp
-		}
-		manager, err := kubeconfig.NewManager()
-		if err != nil {
-			return nil, cobra.ShellCompDirectiveNoFileComp
-		}
-		var matches []string
-		for _, name := range manager.ListClusterNames() {
-			if strings.HasPrefix(name, toComplete) {
-				matches = append(matches, name)
-			}
-		}
-		return matches, cobra.ShellCompDirectiveNoFileComp
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return initSettings()
 	},
+	ValidArgsFunction: completeClusterNames,
 }
 
+// Execute runs the root command. Interrupts cancel the command's context, which
+// stops running authenticator subprocesses.
 func Execute() error {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		var exitErr *ExitError
+		if !errors.As(err, &exitErr) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
 		return err
 	}
 	return nil
 }
 
+// ExitError makes the process exit with Code without printing an error. It
+// passes through the exit status of a command run by shell or exec.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
 func init() {
-	rootCmd.Flags().StringVarP(&profileFlag, "profile", "p", "", "NCP profile name (default: DEFAULT)")
+	rootCmd.PersistentFlags().StringVarP(&profileFlag, "profile", "p", "", "NCP profile name (default: $NCLOUD_PROFILE, config profile, or DEFAULT)")
+	rootCmd.PersistentFlags().StringVar(&authenticatorPathFlag, "authenticator-path", "", "ncp-iam-authenticator binary (default: $NKS_CTX_AUTHENTICATOR, config authenticatorPath, or search)")
+	rootCmd.Flags().StringVarP(&outputFlag, "output", "o", "", "Output format for the cluster list: text or json")
+	rootCmd.Flags().DurationVar(&forFlag, "for", 0, "Switch back to the previous context after this long, e.g. 30m")
 }
 
 func run(cmd *cobra.Command, args []string) error {
+	revertDue()
 	if len(args) == 0 {
-		return runSync()
+		if readOnlyFlag {
+			return fmt.Errorf("--read-only needs a cluster name")
+		}
+		if forFlag != 0 {
+			return fmt.Errorf("--for needs a cluster name")
+		}
+		if namespaceFlag != "" {
+			return fmt.Errorf("--namespace needs a cluster name; use 'kubectl nks-ctx ns %s' for the current context", namespaceFlag)
+		}
+		return runSync(cmd.Context(), false)
 	}
-	return runSwitch(args[0])
-}
-
-// runSync fetches all NKS clusters, generates kubeconfig entries via
-// ncp-iam-authenticator, and displays the cluster list.
-func runSync() error {
-	cfg, err := ncp.LoadConfig(profileFlag)
-	if err != nil {
-		return err
+	if dryRunFlag {
+		return fmt.Errorf("--dry-run previews a sync and cannot be combined with a cluster name")
 	}
-
-	// Verify ncp-iam-authenticator is available
-	authenticator := ncp.NewAuthenticator(profileFlag)
-	if !authenticator.IsInstalled() {
-		return fmt.Errorf(
-			"ncp-iam-authenticator not found.\n" +
-				"Install it from: https://guide.ncloud-docs.com/docs/nks-nkstoken",
-		)
+	if forFlag < 0 {
+		return fmt.Errorf("--for must be positive")
 	}
-
-	client := ncp.NewClientFromConfig(cfg)
-
-	clusters, err := client.ListClusters()
-	if err != nil {
-		return fmt.Errorf("failed to list clusters: %w", err)
+	if args[0] == "-" {
+		if readOnlyFlag || namespaceFlag != "" {
+			return fmt.Errorf("--read-only and --namespace need a cluster name, not '-'")
+		}
+		return runSwitchBack(forFlag)
 	}
+	return runSwitch(cmd.Context(), args[0], namespaceFlag)
+}
 
-	if len(clusters) == 0 {
-		fmt.Println("No clusters found.")
-		return nil
+type clusterRow struct {
+	Name    string `json:"name"`
+	Region  string `json:"region"`
+	Status  string `json:"status"`
+	Context string `json:"context,omitempty"`
+	Current bool   `json:"current"`
+	Error   string `json:"error,omitempty"`
+	// Derived lists the contexts created from this one with 'context create'.
+	Derived []string `json:"derived,omitempty"`
+}
+
+// printClusters lists clusters in the selected output format, each followed
+// by its derived contexts; "*" marks the current context. failures maps
+// cluster UUIDs to the reason their sync failed.
+func printClusters(manager *kubeconfig.Manager, clusters []ncp.Cluster, failures map[string]string) error {
+	current := manager.GetCurrentContext()
+	rows := make([]clusterRow, 0, len(clusters))
+	for _, cluster := range clusters {
+		ctxName := findClusterContext(manager, cluster)
+		rows = append(rows, clusterRow{
+			Name:    cluster.Name,
+			Region:  cluster.Region,
+			Status:  cluster.Status,
+			Context: ctxName,
+			Current: ctxName != "" && ctxName == current,
+			Error:   failures[cluster.UUID],
+			Derived: manager.DerivedContexts(cluster.UUID),
+		})
 	}
 
-	// Load kubeconfig to check existing entries
-	kubeconfigPath := kubeconfig.DefaultPath()
-	manager, err := kubeconfig.NewManager()
-	if err != nil {
-		return fmt.Errorf("failed to read kubeconfig: %w", err)
+	if outputFlag == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
 	}
 
-	// Sync each cluster to kubeconfig (skip if already exists)
-	syncCount := 0
-	skipCount := 0
-	for _, cluster := range clusters {
-		if ctxName := manager.FindContextByCluster(cluster.Name); ctxName != "" {
-			skipCount++
-			continue
+	for _, row := range rows {
+		marker := "  "
+		if row.Current {
+			marker = "* "
 		}
-		if err := authenticator.UpdateKubeconfig(cluster, kubeconfigPath, false); err != nil {
-			fmt.Fprintf(os.Stderr, "  Warning: failed to sync %s: %v\n", cluster.Name, err)
-			continue
+		fmt.Printf("%s%s\n", marker, row.Name)
+		for _, name := range row.Derived {
+			marker = "  "
+			if name == current {
+				marker = "* "
+			}
+			if manager.IsReadOnly(name) {
+				name += " (read-only)"
+			}
+			fmt.Printf("%s  %s\n", marker, name)
 		}
-		syncCount++
 	}
 
-	if syncCount > 0 || skipCount > 0 {
-		fmt.Printf("Synced %d cluster(s), skipped %d already configured. (%d total)\n\n", syncCount, skipCount, len(clusters))
-	}
+	return nil
+}
 
-	// Reload kubeconfig if new clusters were synced
-	if syncCount > 0 {
-		manager, err = kubeconfig.NewManager()
-		if err != nil {
-			return fmt.Errorf("failed to read kubeconfig: %w", err)
-		}
+// findClusterContext locates a cluster's context by UUID, then by name.
+func findClusterContext(manager *kubeconfig.Manager, cluster ncp.Cluster) string {
+	if ctxName := manager.FindContextByClusterUUID(cluster.UUID); ctxName != "" {
+		return ctxName
 	}
+	return manager.FindContextByCluster(cluster.Name)
+}
 
-	current := manager.GetCurrentContext()
-	for _, cluster := range clusters {
-		ctxName := manager.FindContextByCluster(cluster.Name)
-		marker := "  "
-		if ctxName != "" && ctxName == current {
-			marker = "* "
+// completeClusterNames completes the cluster-name argument from kubeconfig,
+// listing each cluster's derived contexts after it.
+func completeClusterNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	if err := initSettings(); err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	manager, err := newMergedManager()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	clusters := manager.ListClusterNames()
+	sort.Strings(clusters)
+	var matches []string
+	for _, name := range clusters {
+		candidates := []string{name}
+		if ctxName := manager.FindContextByCluster(name); ctxName != "" {
+			if meta := manager.Meta(ctxName); meta != nil {
+				candidates = append(candidates, manager.DerivedContexts(meta.ClusterUUID)...)
+			}
+		}
+		for _, c := range candidates {
+			if strings.HasPrefix(c, toComplete) {
+				matches = append(matches, c)
+			}
 		}
-		fmt.Printf("%s%s\n", marker, cluster.Name)
 	}
-
-	return nil
+	return matches, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
 }
 
-// runSwitch changes the current kubeconfig context to the specified cluster.
-func runSwitch(clusterName string) error {
-	manager, err := kubeconfig.NewManager()
+// runSwitch changes the current kubeconfig context to the specified cluster,
+// or its read-only context with --read-only, and sets its namespace or
+// restores the namespace last used there.
+// In the files layout the context is found in the per-cluster files and
+// current-context is written to the managed kubeconfig.
+func runSwitch(ctx context.Context, clusterName, namespace string) error {
+	manager, err := newMergedManager()
 	if err != nil {
 		return fmt.Errorf("failed to read kubeconfig: %w", err)
 	}
@@ -160,11 +247,102 @@ func runSwitch(clusterName string) error {
 			clusterName,
 		)
 	}
+	if readOnlyFlag {
+		name, created, err := readOnlyContext(ctx, manager, contextName)
+		if err != nil {
+			return err
+		}
+		if created {
+			if manager, err = newMergedManager(); err != nil {
+				return fmt.Errorf("failed to read kubeconfig: %w", err)
+			}
+		}
+		contextName = name
+	}
 
-	if err := manager.SwitchContext(contextName); err != nil {
-		return fmt.Errorf("failed to switch context: %w", err)
+	if namespace != "" {
+		if err := checkNamespace(ctx, manager, contextName, namespace); err != nil {
+			return err
+		}
+	}
+	if err := switchTo(manager, contextName, forFlag); err != nil {
+		return err
 	}
 
-	fmt.Printf("Switched to context \"%s\"\n", contextName)
+	if namespace != "" {
+		if err := setNamespace(manager, contextName, namespace); err != nil {
+			return err
+		}
+		fmt.Printf("Active namespace is \"%s\"\n", namespace)
+	} else if restored := restoreNamespace(manager, contextName); restored != "" {
+		fmt.Printf("Active namespace is \"%s\" (restored)\n", restored)
+	}
+	return nil
+}
+
+// switchTo makes contextName current and records the switch in the history.
+// Switching to a cluster protected by a guard needs confirmation. If
+// revertAfter or the guard's revertAfter is set, whichever is shorter, a
+// switch back to the previous context is scheduled; switching with
+// revertAfter to the context that is already current moves its pending
+// switch back instead.
+func switchTo(manager *kubeconfig.Manager, contextName string, revertAfter time.Duration) error {
+	from := manager.GetCurrentContext()
+	if revertAfter > 0 && from == "" {
+		return fmt.Errorf("no current context to switch back to; --for needs one")
+	}
+	extend := from == contextName && revertAfter > 0
+	guard := guardFor(manager, contextName)
+	if guard != nil && guard.Revert() > 0 && (revertAfter == 0 || guard.Revert() < revertAfter) {
+		revertAfter = guard.Revert()
+	}
+	if guard != nil && from != contextName {
+		if err := confirmGuard(manager, guard, contextName); err != nil {
+			return err
+		}
+	}
+
+	var revertAt time.Time
+	revertTo := from
+	if revertAfter > 0 && (from != contextName || extend) {
+		revertAt = time.Now().Add(revertAfter)
+	}
+	if from == contextName {
+		if extend {
+			to, err := extendRevert(manager.Path(), contextName, revertAt)
+			if err != nil {
+				return err
+			}
+			revertTo = to
+		}
+	} else {
+		if err := manager.SwitchContext(contextName); err != nil {
+			return fmt.Errorf("failed to switch context: %w", err)
+		}
+		recordSwitch(manager.Path(), from, contextName, revertAt)
+	}
+
+	msg := fmt.Sprintf("Switched to context \"%s\"", contextName)
+	if guard != nil {
+		msg = paint(os.Stdout, "red", msg+" (protected)")
+	}
+	fmt.Println(msg)
+	if !revertAt.IsZero() {
+		if err := startRevertHelper(); err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: cannot start the revert helper: %v; the switch back happens on the next nks-ctx run after %s\n", err, revertAt.Format("15:04"))
+		}
+		fmt.Printf("Switching back to \"%s\" at %s (in %s)\n", revertTo, revertAt.Format("15:04"), revertAfter)
+	}
+	if manager.IsReadOnly(contextName) {
+		user, groups := manager.Impersonation(contextName)
+		fmt.Printf("Read-only: requests are made as user %s, groups %s\n", user, strings.Join(groups, ","))
+	}
+	if filesLayout() {
+		if idx, err := kubeconfig.LoadIndex(kubeconfig.FilesDir()); err == nil {
+			if e, err := idx.Find(contextName); err == nil && !kubeconfigEnvIncludes([]kubeconfig.IndexEntry{*e}) {
+				fmt.Fprintf(os.Stderr, "kubectl does not see %s yet. Run:\n  eval \"$(kubectl nks-ctx env)\"\n", e.File)
+			}
+		}
+	}
 	return nil
 }
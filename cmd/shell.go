@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/consol-lee/nks-ctx/pkg/kubeconfig"
+	"github.com/consol-lee/nks-ctx/pkg/ncp"
+	"golang.org/x/term"
+)
+
+// ClusterEnv names the cluster of an isolated shell or exec; it is also how
+// nested shells are detected.
+const ClusterEnv = "NKS_CTX_CLUSTER"
+
+var shellForce bool
+
+var shellCmd = &cobra.Command{
+	Use:   "shell <cluster-name>",
+	Short: "Start a shell whose kubectl uses one cluster, without changing current-context",
+	Long: `Write a temporary kubeconfig holding only the cluster's context and start
+$SHELL with KUBECONFIG pointing at it and NKS_CTX_CLUSTER set to the cluster
+name. Other terminals keep their cluster; the temporary file is removed when
+the shell exits. The cluster is looked up like 'kubectl nks-ctx <cluster>'.
+
+Starting a shell from inside another one is refused, since kubectl would
+silently switch clusters; use --force to nest anyway.
+
+Add NKS_CTX_CLUSTER to your prompt to see which cluster a shell uses, e.g.
+  PS1='${NKS_CTX_CLUSTER:+[$NKS_CTX_CLUSTER] }'"$PS1"`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if current := os.Getenv(ClusterEnv); current != "" && !shellForce {
+			return fmt.Errorf("already in an nks-ctx shell for '%s'; exit it first or use --force", current)
+		}
+
+		kc, err := isolatedKubeconfig(args[0])
+		if err != nil {
+			return err
+		}
+		defer kc.remove()
+
+		shell := os.Getenv("SHELL")
+		if shell == "" {
+			shell = "/bin/sh"
+		}
+		fmt.Fprintf(os.Stderr, "Starting %s for cluster \"%s\" (context \"%s\"). Exit the shell to return.\n", shell, kc.cluster, kc.context)
+
+		c := exec.Command(shell)
+		c.Env = kc.environ()
+		return runChild(c)
+	},
+	ValidArgsFunction: completeClusterNames,
+}
+
+func init() {
+	shellCmd.Flags().BoolVar(&shellForce, "force", false, "Start a shell even inside another nks-ctx shell")
+	rootCmd.AddCommand(shellCmd)
+}
+
+// tempKubeconfig is a single-context kubeconfig written for one child process.
+type tempKubeconfig struct {
+	path    string
+	context string
+	cluster string
+}
+
+// isolatedKubeconfig looks up clusterName like runSwitch and writes its
+// context to a temporary kubeconfig.
+func isolatedKubeconfig(clusterName string) (*tempKubeconfig, error) {
+	manager, err := newMergedManager()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+	contextName, err := manager.FindContext(clusterName)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"context not found for '%s'.\nRun 'kubectl nks-ctx' first to sync clusters.",
+			clusterName,
+		)
+	}
+
+	return writeTempKubeconfig(manager, contextName)
+}
+
+// writeTempKubeconfig writes contextName to a temporary 0600 kubeconfig.
+// Call remove when done.
+func writeTempKubeconfig(manager *kubeconfig.Manager, contextName string) (*tempKubeconfig, error) {
+	exported, err := manager.Export([]string{contextName}, kubeconfig.ExportOptions{Local: true})
+	if err != nil {
+		return nil, err
+	}
+	data, err := exported.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.CreateTemp("", "nks-ctx-*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	kc := &tempKubeconfig{path: f.Name(), context: contextName, cluster: manager.Labels(contextName)["name"]}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		kc.remove()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		kc.remove()
+		return nil, err
+	}
+	return kc, nil
+}
+
+func (kc *tempKubeconfig) remove() {
+	os.Remove(kc.path)
+}
+
+// environ returns the current environment with KUBECONFIG and NKS_CTX_CLUSTER
+// pointing at the temporary kubeconfig.
+func (kc *tempKubeconfig) environ() []string {
+	env := ncp.WithoutEnv(os.Environ(), "KUBECONFIG", ClusterEnv)
+	return append(env, "KUBECONFIG="+kc.path, ClusterEnv+"="+kc.cluster)
+}
+
+// runChild runs c with nks-ctx's stdio. SIGTERM and SIGHUP sent to nks-ctx
+// are forwarded to c. On a terminal, Ctrl-C and Ctrl-\ already reach c through
+// the terminal's process group; otherwise SIGINT and SIGQUIT are forwarded
+// too. A non-zero exit is returned as *ExitError with the same status, or
+// 128+signal if c was killed.
+func runChild(c *exec.Cmd) error {
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	// All four are caught so nks-ctx outlives c and removes its files.
+	tty := term.IsTerminal(int(os.Stdin.Fd()))
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGHUP, os.Interrupt, syscall.SIGQUIT)
+	defer signal.Stop(sigs)
+
+	if err := c.Start(); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-sigs:
+				if tty && (sig == os.Interrupt || sig == syscall.SIGQUIT) {
+					continue
+				}
+				c.Process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := c.Wait()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return err
+	}
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return &ExitError{Code: 128 + int(status.Signal())}
+	}
+	return &ExitError{Code: exitErr.ExitCode()}
+}
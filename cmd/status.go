@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/consol-lee/nks-ctx/pkg/state"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the current context and any pending switch back",
+	Long: `Show the current context, its cluster and namespace, whether it is
+read-only or protected by a guard, and the time left before a time-boxed
+switch (--for, or a guard's revertAfter) switches back.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		revertDue()
+		manager, err := newMergedManager()
+		if err != nil {
+			return fmt.Errorf("failed to read kubeconfig: %w", err)
+		}
+		s, err := state.Load(state.DefaultPath())
+		if err != nil {
+			return err
+		}
+
+		current := manager.GetCurrentContext()
+		st := contextStatus{Kubeconfig: manager.Path(), Context: current}
+		if current != "" {
+			labels := manager.Labels(current)
+			st.Cluster, st.Region, st.Profile = labels["name"], labels["region"], labels["profile"]
+			st.Namespace = manager.Namespace(current)
+			st.ReadOnly = manager.IsReadOnly(current)
+			if g := guardFor(manager, current); g != nil {
+				st.Guard = g.String()
+			}
+		}
+		if r, ok := s.PendingRevert(manager.Path()); ok && r.Context == current {
+			st.Revert = &revertStatus{To: r.To, At: r.At, Remaining: time.Until(r.At).Round(time.Second).String()}
+		}
+
+		if outputFlag == "json" {
+			return encodeJSON(st)
+		}
+		if current == "" {
+			fmt.Println("No current context.")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "Context:\t%s\n", current)
+		if st.Cluster != "" {
+			fmt.Fprintf(tw, "Cluster:\t%s\n", st.Cluster)
+		}
+		if st.Region != "" {
+			fmt.Fprintf(tw, "Region:\t%s\n", st.Region)
+		}
+		if st.Profile != "" {
+			fmt.Fprintf(tw, "Profile:\t%s\n", st.Profile)
+		}
+		if st.Namespace != "" {
+			fmt.Fprintf(tw, "Namespace:\t%s\n", st.Namespace)
+		}
+		if st.ReadOnly {
+			fmt.Fprintf(tw, "Read-only:\tyes\n")
+		}
+		if st.Guard != "" {
+			fmt.Fprintf(tw, "Protected:\t%s\n", paint(os.Stdout, "red", "guard "+st.Guard))
+		}
+		if st.Revert != nil {
+			fmt.Fprintf(tw, "Switching back:\tto \"%s\" in %s (at %s)\n", st.Revert.To, st.Revert.Remaining, st.Revert.At.Local().Format("15:04:05"))
+		}
+		return tw.Flush()
+	},
+}
+
+type contextStatus struct {
+	Kubeconfig string        `json:"kubeconfig"`
+	Context    string        `json:"context"`
+	Cluster    string        `json:"cluster,omitempty"`
+	Region     string        `json:"region,omitempty"`
+	Profile    string        `json:"profile,omitempty"`
+	Namespace  string        `json:"namespace,omitempty"`
+	ReadOnly   bool          `json:"readOnly"`
+	Guard      string        `json:"guard,omitempty"`
+	Revert     *revertStatus `json:"revert,omitempty"`
+}
+
+type revertStatus struct {
+	To        string    `json:"to"`
+	At        time.Time `json:"at"`
+	Remaining string    `json:"remaining"`
+}
+
+func init() {
+	statusCmd.Flags().StringVarP(&outputFlag, "output", "o", "", "Output format: text or json")
+	rootCmd.AddCommand(statusCmd)
+}
@@ -0,0 +1,507 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/consol-lee/nks-ctx/pkg/kubeconfig"
+	"github.com/consol-lee/nks-ctx/pkg/ncp"
+)
+
+var dryRunFlag bool
+
+var refreshCmd = &cobra.Command{
+	Use:   "refresh",
+	Short: "Regenerate kubeconfig entries for all clusters",
+	Long: `Run ncp-iam-authenticator update-kubeconfig --overwrite for every cluster,
+including those already in kubeconfig, then re-apply context names, metadata,
+the authenticator path and pinned profiles.
+
+With --dry-run the change plan is computed without running the authenticator;
+server addresses and certificates it would re-fetch are not shown.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSync(cmd.Context(), true)
+	},
+}
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove contexts of clusters that no longer exist",
+	Long: `Remove managed contexts whose cluster no longer exists in the current
+profile, together with their cluster and user entries when nothing else
+uses them. Contexts synced from other profiles or outside the configured
+regions are left alone, and nothing is removed unless every regional
+endpoint answered.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runPrune()
+	},
+}
+
+func init() {
+	rootCmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "Print the kubeconfig changes a sync would make without writing them")
+	for _, c := range []*cobra.Command{refreshCmd, pruneCmd} {
+		c.Flags().BoolVar(&dryRunFlag, "dry-run", false, "Print the kubeconfig changes without writing them")
+		c.Flags().StringVarP(&outputFlag, "output", "o", "", "Output format: text or json")
+	}
+	rootCmd.AddCommand(refreshCmd, pruneCmd)
+}
+
+// printConfigWarnings reports malformed configure file lines that did not
+// prevent loading cfg.
+func printConfigWarnings(cfg *ncp.Config) {
+	for _, w := range cfg.Warnings {
+		fmt.Fprintf(os.Stderr, "  Warning: %s\n", w)
+	}
+}
+
+// runSync fetches all NKS clusters, generates kubeconfig entries via
+// ncp-iam-authenticator, and displays the cluster list. With refresh, entries
+// that already exist are regenerated too.
+func runSync(ctx context.Context, refresh bool) error {
+	cfg, err := ncp.LoadConfig(profileFlag)
+	if err != nil {
+		return err
+	}
+	printConfigWarnings(cfg)
+
+	// Verify ncp-iam-authenticator is available
+	authenticator := newAuthenticator(profileFlag)
+	authenticator.SetCredentials(cfg)
+	if !authenticator.IsInstalled() {
+		return fmt.Errorf(
+			"ncp-iam-authenticator not found at %s.\n"+
+				"Install it from: https://guide.ncloud-docs.com/docs/nks-nkstoken\n"+
+				"or point --authenticator-path at it.",
+			authenticator.Path(),
+		)
+	}
+	if !dryRunFlag {
+		if _, err := authenticator.CheckVersion(); err != nil {
+			return fmt.Errorf("%w\nUpgrade it from: https://guide.ncloud-docs.com/docs/nks-nkstoken", err)
+		}
+	}
+	command, err := execCommand(cfg, authenticator)
+	if err != nil {
+		return err
+	}
+
+	client := ncp.NewClientFromConfig(cfg)
+
+	clusters, err := client.ListClusters()
+	if err != nil {
+		return fmt.Errorf("failed to list clusters: %w", err)
+	}
+	if !dryRunFlag {
+		if err := ncp.UpdateInventory(ncp.DefaultInventoryPath(), cfg.Profile, clusters, time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: cannot cache the cluster list: %v\n", err)
+		}
+	}
+	clusters = filterRegions(clusters, settings.Regions)
+
+	if len(clusters) == 0 && outputFlag == "text" && !dryRunFlag {
+		fmt.Println("No clusters found.")
+		return nil
+	}
+
+	s := &syncer{ctx: ctx, cfg: cfg, authenticator: authenticator, command: command, refresh: refresh}
+	if filesLayout() {
+		return s.syncFiles(clusters)
+	}
+
+	original, manager, err := s.sync(kubeconfigPath(), clusters)
+	if err != nil {
+		return err
+	}
+	s.printSummary(clusters)
+	if dryRunFlag {
+		return printPlan(original, manager)
+	}
+	return printClusters(manager, clusters, s.failures)
+}
+
+// syncer syncs clusters into one kubeconfig file at a time and accumulates
+// the counts for the summary.
+type syncer struct {
+	ctx           context.Context
+	cfg           *ncp.Config
+	authenticator *ncp.Authenticator
+	command       string
+	refresh       bool
+
+	synced   int
+	skipped  int
+	failures map[string]string
+}
+
+// sync adds missing clusters (all clusters when refreshing) to the kubeconfig
+// at path, then tags, names and pins their contexts. Changes are saved unless
+// --dry-run is set. It returns the kubeconfig as it was and as it is now (or
+// would be).
+func (s *syncer) sync(path string, clusters []ncp.Cluster) (original, manager *kubeconfig.Manager, err error) {
+	if s.failures == nil {
+		s.failures = make(map[string]string)
+	}
+	manager, err = kubeconfig.NewManagerForPath(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+	original = manager.Clone()
+
+	// Sync each cluster to kubeconfig (skip if already exists, unless refreshing)
+	ran := false
+	for _, cluster := range clusters {
+		existing := findClusterContext(manager, cluster)
+		if existing != "" && !s.refresh {
+			s.skipped++
+			continue
+		}
+		if dryRunFlag {
+			if existing == "" {
+				manager.AddPlanned(cluster.Name, cluster.UUID, cluster.Region, s.command)
+			}
+			s.synced++
+			continue
+		}
+		if err := s.authenticator.UpdateKubeconfig(s.ctx, cluster, path, s.refresh); err != nil {
+			if s.ctx.Err() != nil {
+				return nil, nil, fmt.Errorf("sync interrupted: %w", err)
+			}
+			s.failures[cluster.UUID] = failureReason(err)
+			continue
+		}
+		s.synced++
+		ran = true
+	}
+
+	// Reload kubeconfig if the authenticator changed it
+	changed := false
+	if ran {
+		manager, err = kubeconfig.NewManagerForPath(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read kubeconfig: %w", err)
+		}
+		if s.refresh {
+			changed = dropDuplicateContexts(manager, clusters)
+		}
+	}
+
+	tagged, err := tagClusters(manager, clusters, s.cfg.Profile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to update kubeconfig contexts: %w", err)
+	}
+	changed = tagged || changed
+	changed = setExecCommand(manager, clusters, s.command) || changed
+	changed = pinExecUsers(manager, clusters, s.cfg) || changed
+	for _, cluster := range clusters {
+		for _, name := range manager.DerivedContexts(cluster.UUID) {
+			changed = manager.RefreshReadOnly(name) || changed
+		}
+	}
+	if len(clusters) == 1 && filesLayout() {
+		// A per-cluster file selects its own context when used on its own.
+		if ctxName := findClusterContext(manager, clusters[0]); ctxName != "" {
+			changed = manager.SetCurrentContext(ctxName) || changed
+		}
+	}
+
+	if changed && !dryRunFlag {
+		if err := manager.Save(); err != nil {
+			return nil, nil, fmt.Errorf("failed to update kubeconfig: %w", err)
+		}
+	}
+	return original, manager, nil
+}
+
+// printSummary prints the sync counts and failures in text output.
+func (s *syncer) printSummary(clusters []ncp.Cluster) {
+	if (s.synced == 0 && s.skipped == 0 && len(s.failures) == 0) || outputFlag != "text" || dryRunFlag {
+		return
+	}
+	verb := "Synced"
+	if s.refresh {
+		verb = "Refreshed"
+	}
+	fmt.Printf("%s %d cluster(s), skipped %d already configured, %d failed. (%d total)\n", verb, s.synced, s.skipped, len(s.failures), len(clusters))
+	for _, cluster := range clusters {
+		if reason, ok := s.failures[cluster.UUID]; ok {
+			fmt.Printf("  ! %s: %s\n", cluster.Name, reason)
+		}
+	}
+	fmt.Println()
+}
+
+// syncFiles syncs each cluster into its own file under ~/.kube/nks/<profile>
+// and records it in the index.
+func (s *syncer) syncFiles(clusters []ncp.Cluster) error {
+	dir := kubeconfig.FilesDir()
+	idx, err := kubeconfig.LoadIndex(dir)
+	if err != nil {
+		return err
+	}
+
+	var plans [][2]*kubeconfig.Manager
+	for _, cluster := range clusters {
+		path := kubeconfig.ClusterFile(dir, s.cfg.Profile, cluster.Name)
+		original, manager, err := s.sync(path, []ncp.Cluster{cluster})
+		if err != nil {
+			return err
+		}
+		if dryRunFlag {
+			plans = append(plans, [2]*kubeconfig.Manager{original, manager})
+			continue
+		}
+		if ctxName := findClusterContext(manager, cluster); ctxName != "" && manager.Meta(ctxName) != nil {
+			idx.Put(kubeconfig.IndexEntry{ClusterMeta: *manager.Meta(ctxName), Context: ctxName, File: path})
+		}
+	}
+
+	s.printSummary(clusters)
+	if dryRunFlag {
+		return printFilePlans(plans)
+	}
+	if err := idx.Save(); err != nil {
+		return fmt.Errorf("failed to update %s: %w", idx.Path(), err)
+	}
+
+	manager, err := newMergedManager()
+	if err != nil {
+		return fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+	if err := printClusters(manager, clusters, s.failures); err != nil {
+		return err
+	}
+	if outputFlag == "text" && !kubeconfigEnvIncludes(idx.Entries) {
+		fmt.Fprintf(os.Stderr, "\nkubectl does not see all synced files yet. Run:\n  eval \"$(kubectl nks-ctx env)\"\n")
+	}
+	return nil
+}
+
+// failureReason returns a one-line cause for the sync summary.
+func failureReason(err error) string {
+	var aerr *ncp.AuthenticatorError
+	if errors.As(err, &aerr) {
+		return aerr.Reason()
+	}
+	return err.Error()
+}
+
+// dropDuplicateContexts removes the contexts ncp-iam-authenticator re-created
+// under its default name for clusters whose tagged context was renamed.
+func dropDuplicateContexts(manager *kubeconfig.Manager, clusters []ncp.Cluster) bool {
+	changed := false
+	for _, cluster := range clusters {
+		names := manager.ContextsForCluster(cluster.UUID)
+		tagged := 0
+		for _, name := range names {
+			if manager.Meta(name) != nil {
+				tagged++
+			}
+		}
+		if tagged == 0 {
+			continue
+		}
+		for _, name := range names {
+			if manager.Meta(name) == nil {
+				manager.RemoveContext(name)
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// runPrune removes managed contexts of the current profile whose cluster no
+// longer exists.
+func runPrune() error {
+	cfg, err := ncp.LoadConfig(profileFlag)
+	if err != nil {
+		return err
+	}
+	printConfigWarnings(cfg)
+
+	clusters, err := ncp.NewClientFromConfig(cfg).ListClustersStrict()
+	if err != nil {
+		return fmt.Errorf("refusing to prune with an incomplete cluster list: %w", err)
+	}
+	live := make(map[string]bool, len(clusters))
+	for _, c := range clusters {
+		live[c.UUID] = true
+	}
+	selected := make(map[string]bool, len(settings.Regions))
+	for _, r := range settings.Regions {
+		selected[r] = true
+	}
+	stale := func(meta kubeconfig.ClusterMeta) bool {
+		if meta.Profile != cfg.Profile || live[meta.ClusterUUID] {
+			return false
+		}
+		return len(selected) == 0 || selected[meta.Region]
+	}
+
+	if filesLayout() {
+		return pruneFiles(stale)
+	}
+
+	manager, err := newManager()
+	if err != nil {
+		return fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+	original := manager.Clone()
+
+	var pruned []string
+	for _, name := range manager.ManagedContexts() {
+		if !stale(*manager.Meta(name)) {
+			continue
+		}
+		if err := manager.RemoveContext(name); err != nil {
+			return err
+		}
+		pruned = append(pruned, name)
+	}
+
+	if dryRunFlag {
+		return printPlan(original, manager)
+	}
+	if len(pruned) == 0 {
+		fmt.Println("Nothing to prune.")
+		return nil
+	}
+	if err := manager.Save(); err != nil {
+		return fmt.Errorf("failed to update kubeconfig: %w", err)
+	}
+	for _, name := range pruned {
+		fmt.Printf("Pruned context \"%s\"\n", name)
+	}
+	return nil
+}
+
+// pruneFiles deletes the per-cluster files of stale clusters and drops them
+// from the index.
+func pruneFiles(stale func(kubeconfig.ClusterMeta) bool) error {
+	idx, err := kubeconfig.LoadIndex(kubeconfig.FilesDir())
+	if err != nil {
+		return err
+	}
+
+	var pruned []kubeconfig.IndexEntry
+	for _, e := range idx.Entries {
+		if stale(e.ClusterMeta) {
+			pruned = append(pruned, e)
+		}
+	}
+
+	if dryRunFlag {
+		plans := make([]changePlan, 0, len(pruned))
+		for _, e := range pruned {
+			plans = append(plans, changePlan{
+				Kubeconfig: e.File,
+				Changes:    []kubeconfig.Change{{Kind: "file", Name: e.File, Action: "remove"}},
+			})
+			if outputFlag == "text" {
+				fmt.Printf("Would remove %s (context \"%s\")\n", e.File, e.Context)
+			}
+		}
+		if outputFlag == "json" {
+			return encodeJSON(plans)
+		}
+		fmt.Printf("Dry run: %d file(s) not removed\n", len(pruned))
+		return nil
+	}
+
+	if len(pruned) == 0 {
+		fmt.Println("Nothing to prune.")
+		return nil
+	}
+	for _, e := range pruned {
+		if err := os.Remove(e.File); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		idx.Remove(e.File)
+		fmt.Printf("Pruned context \"%s\" (%s)\n", e.Context, e.File)
+	}
+	return idx.Save()
+}
+
+type changePlan struct {
+	Kubeconfig string              `json:"kubeconfig"`
+	Changes    []kubeconfig.Change `json:"changes"`
+}
+
+func newChangePlan(original, planned *kubeconfig.Manager) changePlan {
+	changes := planned.Changes(original)
+	if changes == nil {
+		changes = []kubeconfig.Change{}
+	}
+	return changePlan{Kubeconfig: planned.Path(), Changes: changes}
+}
+
+// printPlan shows what --dry-run would change: a unified YAML diff, or the
+// change plan as JSON with -o json.
+func printPlan(original, planned *kubeconfig.Manager) error {
+	if outputFlag == "json" {
+		return encodeJSON(newChangePlan(original, planned))
+	}
+	if err := printDiff(original, planned); err != nil {
+		return err
+	}
+	if n := len(planned.Changes(original)); n > 0 {
+		fmt.Printf("\nDry run: %d change(s) to %s not written\n", n, planned.Path())
+	} else {
+		fmt.Printf("Dry run: no changes to %s\n", planned.Path())
+	}
+	return nil
+}
+
+// printFilePlans is printPlan for the files layout: one diff per changed
+// file, or a JSON array of change plans.
+func printFilePlans(plans [][2]*kubeconfig.Manager) error {
+	if outputFlag == "json" {
+		out := make([]changePlan, 0, len(plans))
+		for _, p := range plans {
+			if plan := newChangePlan(p[0], p[1]); len(plan.Changes) > 0 {
+				out = append(out, plan)
+			}
+		}
+		return encodeJSON(out)
+	}
+
+	files, changes := 0, 0
+	for _, p := range plans {
+		if n := len(p[1].Changes(p[0])); n > 0 {
+			if err := printDiff(p[0], p[1]); err != nil {
+				return err
+			}
+			files++
+			changes += n
+		}
+	}
+	fmt.Printf("\nDry run: %d change(s) to %d file(s) not written\n", changes, files)
+	return nil
+}
+
+// printDiff prints the unified YAML diff between two kubeconfigs, if any.
+func printDiff(original, planned *kubeconfig.Manager) error {
+	before, err := original.Marshal()
+	if err != nil {
+		return err
+	}
+	after, err := planned.Marshal()
+	if err != nil {
+		return err
+	}
+	fmt.Print(planned.Diff(before, after))
+	return nil
+}
+
+func encodeJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
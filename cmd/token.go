@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/consol-lee/nks-ctx/pkg/kubeconfig"
+	"github.com/consol-lee/nks-ctx/pkg/ncp"
+)
+
+var (
+	tokenClusterUUID string
+	tokenRegion      string
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Print an ExecCredential for kubectl using nks-ctx credential resolution",
+	Long: `Resolve credentials through the nks-ctx provider chain (including the
+encrypted vault) and run 'ncp-iam-authenticator token' with them.
+
+Kubeconfig users synced from a vault or credential_process profile
+run this command instead of ncp-iam-authenticator directly.
+
+Users pinned to a profile (NKS_CTX_PROFILE in their exec env) always use
+that profile and its API gateway, even if NCLOUD_* keys for another account
+are exported in the calling shell.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := tokenCredentials()
+		if err != nil {
+			return err
+		}
+		cfg.Region = tokenRegion
+
+		authenticator := newAuthenticator(profileFlag)
+		authenticator.SetCredentials(cfg)
+		return authenticator.Token(cmd.Context(), tokenClusterUUID, tokenRegion, os.Stdout)
+	},
+}
+
+func init() {
+	tokenCmd.Flags().StringVar(&tokenClusterUUID, "clusterUuid", "", "NKS cluster UUID")
+	tokenCmd.Flags().StringVar(&tokenRegion, "region", "", "NKS cluster region code")
+	tokenCmd.MarkFlagRequired("clusterUuid")
+	tokenCmd.MarkFlagRequired("region")
+	rootCmd.AddCommand(tokenCmd)
+}
+
+// tokenCredentials resolves credentials for `nks-ctx token`, honouring the
+// profile and API gateway pinned in the exec user's env.
+func tokenCredentials() (*ncp.Config, error) {
+	pinned := os.Getenv(kubeconfig.PinnedProfileEnv)
+	if pinned == "" {
+		return ncp.LoadConfig(profileFlag)
+	}
+
+	cfg, err := ncp.DefaultChain().Without("env").Retrieve(pinned)
+	if err != nil {
+		return nil, fmt.Errorf("credentials for pinned profile '%s' not found: %w", pinned, err)
+	}
+	if gw := os.Getenv("NCLOUD_API_GW"); gw != "" {
+		cfg.ApiURL = gw
+	}
+	return cfg, nil
+}
+
+// needsTokenCommand reports whether ncp-iam-authenticator cannot find the
+// credentials on its own, so kubeconfig users must run `nks-ctx token` instead.
+func needsTokenCommand(cfg *ncp.Config) bool {
+	return cfg.Source != "env" && cfg.Source != "profile"
+}
+
+// execCommand returns the command managed exec users run: `nks-ctx token`
+// when ncp-iam-authenticator cannot find the credentials itself, otherwise the
+// resolved authenticator path, so kubectl does not depend on its PATH.
+func execCommand(cfg *ncp.Config, authenticator *ncp.Authenticator) (string, error) {
+	if needsTokenCommand(cfg) {
+		return os.Executable()
+	}
+	return authenticator.Path(), nil
+}
+
+// setExecCommand points the exec users of the given clusters at command.
+// The authenticator's own args (token --clusterUuid ... --region ...) are kept.
+func setExecCommand(manager *kubeconfig.Manager, clusters []ncp.Cluster, command string) bool {
+	if !filepath.IsAbs(command) {
+		return false
+	}
+
+	changed := false
+	for _, cluster := range clusters {
+		exec := manager.ExecConfig(findClusterContext(manager, cluster))
+		if exec == nil || exec.Command == command {
+			continue
+		}
+		exec.Command = command
+		changed = true
+	}
+	return changed
+}
+
+// pinExecUsers pins the profile, API gateway and region of cfg in the exec
+// users of the given clusters. Credentials that only exist in the environment
+// cannot be pinned to a profile; those users keep following NCLOUD_* keys.
+func pinExecUsers(manager *kubeconfig.Manager, clusters []ncp.Cluster, cfg *ncp.Config) bool {
+	changed := false
+	for _, cluster := range clusters {
+		pin := kubeconfig.ExecPin{APIGateway: cfg.ApiURL, Region: cluster.Region}
+		if cfg.Source != "env" {
+			pin.Profile = cfg.Profile
+		}
+		if manager.PinExec(findClusterContext(manager, cluster), pin) {
+			changed = true
+		}
+	}
+	return changed
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/consol-lee/nks-ctx/pkg/ncp"
+	"github.com/consol-lee/nks-ctx/pkg/vault"
+)
+
+var unlockTimeout time.Duration
+
+var credentialsStoreCmd = &cobra.Command{
+	Use:   "store",
+	Short: "Encrypt a profile's access/secret key into the local vault",
+	Long: `Prompt for an access key, secret key and passphrase, and store the key pair
+encrypted (scrypt + AES-256-GCM) in ~/.ncloud/nks-ctx.vault.
+
+Once stored, remove the plaintext ncloud_access_key_id/ncloud_secret_access_key
+lines from ~/.ncloud/configure. Other commands unlock the vault on demand and
+cache the keys in a background agent (see 'credentials unlock').`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profile := profileFlag
+		if profile == "" {
+			profile = "DEFAULT"
+		}
+
+		v, err := vault.Open(vault.DefaultPath())
+		if err != nil {
+			return err
+		}
+
+		accessKey, err := vault.ReadLine("Access key: ")
+		if err != nil {
+			return err
+		}
+		secretKey, err := vault.ReadSecret("Secret key: ")
+		if err != nil {
+			return err
+		}
+		if accessKey == "" || secretKey == "" {
+			return fmt.Errorf("access key and secret key are required")
+		}
+
+		passphrase, err := vault.ReadSecret("New vault passphrase: ")
+		if err != nil {
+			return err
+		}
+		confirm, err := vault.ReadSecret("Confirm passphrase: ")
+		if err != nil {
+			return err
+		}
+		if passphrase != confirm {
+			return fmt.Errorf("passphrases do not match")
+		}
+		if passphrase == "" {
+			return fmt.Errorf("passphrase must not be empty")
+		}
+
+		if err := v.Put(profile, passphrase, vault.Credentials{AccessKey: accessKey, SecretKey: secretKey}); err != nil {
+			return err
+		}
+		if err := v.Save(); err != nil {
+			return fmt.Errorf("failed to write vault: %w", err)
+		}
+
+		fmt.Printf("Stored credentials for profile '%s' in %s\n", profile, vault.DefaultPath())
+		return nil
+	},
+}
+
+var credentialsUnlockCmd = &cobra.Command{
+	Use:   "unlock",
+	Short: "Unlock a vault profile and cache its keys in the agent",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		provider := ncp.NewVaultProvider("")
+		provider.Timeout = unlockTimeout
+		if _, err := provider.Retrieve(profileFlag); err != nil {
+			return err
+		}
+		fmt.Printf("Vault unlocked for %s\n", unlockTimeout)
+		return nil
+	},
+}
+
+var credentialsLockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Drop all credentials cached by the agent",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := vault.NewAgentClient(vault.DefaultSocketPath()).Lock(); err != nil {
+			return err
+		}
+		fmt.Println("Vault locked")
+		return nil
+	},
+}
+
+var credentialsAgentCmd = &cobra.Command{
+	Use:    "agent",
+	Short:  "Run the credentials cache agent",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return vault.Serve(vault.DefaultSocketPath())
+	},
+}
+
+func init() {
+	credentialsUnlockCmd.Flags().DurationVar(&unlockTimeout, "timeout", vault.DefaultTimeout, "How long unlocked keys stay cached")
+	credentialsCmd.AddCommand(credentialsStoreCmd, credentialsUnlockCmd, credentialsLockCmd, credentialsAgentCmd)
+}
@@ -0,0 +1,357 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// ProjectFileName is the per-repository override file, looked up from the
+// current directory towards the filesystem root.
+const ProjectFileName = ".nks-ctx.yaml"
+
+// Kubeconfig layouts.
+const (
+	LayoutMerged = "merged"
+	LayoutFiles  = "files"
+)
+
+// Defaults of the identity read-only contexts impersonate. Bind the group to
+// a view-only role in each cluster, e.g. the built-in "view" ClusterRole.
+const (
+	DefaultReadOnlyUser  = "nks-ctx:read-only"
+	DefaultReadOnlyGroup = "nks-ctx:view"
+)
+
+// Config holds plugin defaults from ~/.config/nks-ctx/config.yaml,
+// overridden by the nearest .nks-ctx.yaml.
+//
+// Example:
+//
+//	profile: finance
+//	regions: [KR, SGN]
+//	nameTemplate: "{{.Profile}}-{{.Name}}"
+//	kubeconfig: ~/.kube/nks.yaml
+//	output: json
+//	authenticatorPath: /opt/homebrew/bin/ncp-iam-authenticator
+//	layout: files
+//	readOnlyGroup: platform:viewers
+//	guards:
+//	  - name: "prod-*"
+//	    revertAfter: 30m
+//	prompt:
+//	  format: "{{.Profile}}:{{.Name}}"
+//	  colors:
+//	    - name: "prod-*"
+//	      color: red
+type Config struct {
+	// Profile is the NCP profile used when neither --profile nor NCLOUD_PROFILE is set.
+	Profile string `json:"profile,omitempty"`
+	// Regions limits sync to clusters in these region codes.
+	Regions []string `json:"regions,omitempty"`
+	// NameTemplate renames synced contexts. Fields: .Name .UUID .Region .Profile.
+	NameTemplate string `json:"nameTemplate,omitempty"`
+	// Kubeconfig is the kubeconfig file to manage when KUBECONFIG is not set.
+	Kubeconfig string `json:"kubeconfig,omitempty"`
+	// Output is the default output format ("text" or "json").
+	Output string `json:"output,omitempty"`
+	// AuthenticatorPath is the ncp-iam-authenticator binary to use instead of
+	// searching for it. Bare names are looked up in $PATH.
+	AuthenticatorPath string `json:"authenticatorPath,omitempty"`
+	// Layout is LayoutMerged (default) to sync into one kubeconfig, or
+	// LayoutFiles to write one kubeconfig per cluster under ~/.kube/nks.
+	Layout string `json:"layout,omitempty"`
+	// ReadOnlyUser and ReadOnlyGroup are impersonated by --read-only
+	// contexts (default DefaultReadOnlyUser and DefaultReadOnlyGroup).
+	ReadOnlyUser  string `json:"readOnlyUser,omitempty"`
+	ReadOnlyGroup string `json:"readOnlyGroup,omitempty"`
+	// Guards protect matching clusters on switch. Guards of a project file
+	// are added to those of the user file.
+	Guards []Guard `json:"guards,omitempty"`
+	// Prompt configures the prompt command.
+	Prompt Prompt `json:"prompt,omitempty"`
+
+	// Sources lists the files that were loaded, in the order they were applied.
+	Sources []string `json:"-"`
+}
+
+// Dir returns the plugin config directory ($XDG_CONFIG_HOME/nks-ctx or ~/.config/nks-ctx).
+func Dir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "nks-ctx")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "nks-ctx")
+}
+
+// DefaultPath returns the user config file path, or NKS_CTX_CONFIG if set.
+func DefaultPath() string {
+	if p := os.Getenv("NKS_CTX_CONFIG"); p != "" {
+		return p
+	}
+	return filepath.Join(Dir(), "config.yaml")
+}
+
+// Load reads the user config file and applies the nearest project file
+// found from the current directory. Missing files are not an error.
+func Load() (*Config, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		cwd = ""
+	}
+	return LoadFrom(DefaultPath(), cwd)
+}
+
+// LoadFrom reads userPath and applies the nearest project file found from dir.
+func LoadFrom(userPath, dir string) (*Config, error) {
+	cfg := &Config{}
+
+	if err := cfg.apply(userPath, false); err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if project := FindProjectFile(dir); project != "" {
+			if err := cfg.apply(project, true); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return cfg, nil
+}
+
+// FindProjectFile walks up from dir and returns the first .nks-ctx.yaml, or "".
+func FindProjectFile(dir string) string {
+	for {
+		candidate := filepath.Join(dir, ProjectFileName)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// apply merges the file at path over cfg. Only fields set in the file override.
+// A project file comes with a repository and so may not name files to run or
+// write: kubeconfig and authenticatorPath are only read from the user config.
+func (c *Config) apply(path string, project bool) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var layer Config
+	if err := yaml.UnmarshalStrict(raw, &layer); err != nil {
+		return fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	if project {
+		for key, value := range map[string]string{"kubeconfig": layer.Kubeconfig, "authenticatorPath": layer.AuthenticatorPath} {
+			if value != "" {
+				return fmt.Errorf("invalid config %s: %s may only be set in %s", path, key, DefaultPath())
+			}
+		}
+	}
+
+	if layer.Profile != "" {
+		c.Profile = layer.Profile
+	}
+	if layer.Regions != nil {
+		c.Regions = layer.Regions
+	}
+	if layer.NameTemplate != "" {
+		c.NameTemplate = layer.NameTemplate
+	}
+	if layer.Kubeconfig != "" {
+		c.Kubeconfig = expandHome(layer.Kubeconfig, filepath.Dir(path))
+	}
+	if layer.Output != "" {
+		c.Output = layer.Output
+	}
+	if layer.AuthenticatorPath != "" {
+		c.AuthenticatorPath = layer.AuthenticatorPath
+		if strings.ContainsRune(c.AuthenticatorPath, '/') {
+			c.AuthenticatorPath = expandHome(c.AuthenticatorPath, filepath.Dir(path))
+		}
+	}
+	if layer.Layout != "" {
+		if layer.Layout != LayoutMerged && layer.Layout != LayoutFiles {
+			return fmt.Errorf("invalid config %s: layout must be %q or %q", path, LayoutMerged, LayoutFiles)
+		}
+		c.Layout = layer.Layout
+	}
+	if layer.ReadOnlyUser != "" {
+		c.ReadOnlyUser = layer.ReadOnlyUser
+	}
+	if layer.ReadOnlyGroup != "" {
+		c.ReadOnlyGroup = layer.ReadOnlyGroup
+	}
+	for i, g := range layer.Guards {
+		if err := g.validate(); err != nil {
+			return fmt.Errorf("invalid config %s: guards[%d]: %w", path, i, err)
+		}
+	}
+	c.Guards = append(c.Guards, layer.Guards...)
+	if layer.Prompt.Format != "" {
+		if _, err := template.New("prompt").Parse(layer.Prompt.Format); err != nil {
+			return fmt.Errorf("invalid config %s: prompt.format: %w", path, err)
+		}
+		c.Prompt.Format = layer.Prompt.Format
+	}
+	if layer.Prompt.Colors != nil {
+		for i, r := range layer.Prompt.Colors {
+			if err := r.validate(); err != nil {
+				return fmt.Errorf("invalid config %s: prompt.colors[%d]: %w", path, i, err)
+			}
+		}
+		c.Prompt.Colors = layer.Prompt.Colors
+	}
+	c.Sources = append(c.Sources, path)
+	return nil
+}
+
+// expandHome resolves "~/" and paths relative to the config file's directory.
+func expandHome(p, base string) string {
+	if len(p) >= 2 && p[:2] == "~/" {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, p[2:])
+	}
+	if !filepath.IsAbs(p) {
+		return filepath.Join(base, p)
+	}
+	return p
+}
+
+// Match selects clusters by name, profile and region. Every field that is
+// set must match; a Match with none set matches every cluster.
+type Match struct {
+	// Name is a glob pattern (as in path.Match) for the cluster or context name.
+	Name string `json:"name,omitempty"`
+	// Profile and Region match the cluster's NCP profile and region code.
+	Profile string `json:"profile,omitempty"`
+	Region  string `json:"region,omitempty"`
+}
+
+func (m Match) validate() error {
+	if _, err := path.Match(m.Name, ""); err != nil {
+		return fmt.Errorf("invalid name pattern %q", m.Name)
+	}
+	return nil
+}
+
+// Matches reports whether a cluster is selected. Name is matched against the
+// cluster name and the context name.
+func (m Match) Matches(clusterName, contextName, profile, region string) bool {
+	if m.Name != "" {
+		cluster, _ := path.Match(m.Name, clusterName)
+		ctx, _ := path.Match(m.Name, contextName)
+		if !cluster && !ctx {
+			return false
+		}
+	}
+	return (m.Profile == "" || m.Profile == profile) && (m.Region == "" || strings.EqualFold(m.Region, region))
+}
+
+// String describes what is matched, e.g. "name=prod-*,region=KR".
+func (m Match) String() string {
+	var parts []string
+	for _, kv := range [][2]string{{"name", m.Name}, {"profile", m.Profile}, {"region", m.Region}} {
+		if kv[1] != "" {
+			parts = append(parts, kv[0]+"="+kv[1])
+		}
+	}
+	if len(parts) == 0 {
+		return "all clusters"
+	}
+	return strings.Join(parts, ",")
+}
+
+// Guard asks for confirmation before switching to a matching cluster.
+type Guard struct {
+	Match
+	// RevertAfter, if set, switches back to the previous context after this
+	// long, e.g. "30m".
+	RevertAfter string `json:"revertAfter,omitempty"`
+}
+
+func (g Guard) validate() error {
+	if err := g.Match.validate(); err != nil {
+		return err
+	}
+	if g.RevertAfter != "" {
+		if d, err := time.ParseDuration(g.RevertAfter); err != nil || d <= 0 {
+			return fmt.Errorf("invalid revertAfter %q", g.RevertAfter)
+		}
+	}
+	return nil
+}
+
+// Revert returns how long after a switch to revert, or 0.
+func (g Guard) Revert() time.Duration {
+	d, _ := time.ParseDuration(g.RevertAfter)
+	return d
+}
+
+// Guard returns the first guard that matches a cluster, or nil.
+func (c *Config) Guard(clusterName, contextName, profile, region string) *Guard {
+	for i := range c.Guards {
+		if c.Guards[i].Matches(clusterName, contextName, profile, region) {
+			return &c.Guards[i]
+		}
+	}
+	return nil
+}
+
+// PromptColors lists the colour names prompt colour rules may use.
+var PromptColors = []string{"red", "green", "yellow", "blue", "magenta", "cyan", "bold"}
+
+// Prompt configures the prompt command.
+type Prompt struct {
+	// Format is the default template for --format.
+	Format string `json:"format,omitempty"`
+	// Colors colour the prompt of matching clusters. The first match wins.
+	Colors []ColorRule `json:"colors,omitempty"`
+}
+
+// ColorRule colours the prompt of the clusters it matches.
+type ColorRule struct {
+	Match
+	// Color is one of PromptColors.
+	Color string `json:"color"`
+}
+
+func (r ColorRule) validate() error {
+	if err := r.Match.validate(); err != nil {
+		return err
+	}
+	for _, c := range PromptColors {
+		if r.Color == c {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown color %q (use %s)", r.Color, strings.Join(PromptColors, ", "))
+}
+
+// PromptColor returns the colour of the first prompt colour rule that matches
+// a cluster, or "".
+func (c *Config) PromptColor(clusterName, contextName, profile, region string) string {
+	for _, r := range c.Prompt.Colors {
+		if r.Matches(clusterName, contextName, profile, region) {
+			return r.Color
+		}
+	}
+	return ""
+}
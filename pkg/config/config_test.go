@@ -0,0 +1,238 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadFrom_ProjectOverridesUser(t *testing.T) {
+	tmpDir := t.TempDir()
+	userPath := filepath.Join(tmpDir, "config.yaml")
+	user := `profile: DEFAULT
+regions: [KR, SGN]
+nameTemplate: "{{.Name}}"
+output: json
+kubeconfig: kube/nks.yaml
+authenticatorPath: bin/ncp-iam-authenticator
+readOnlyGroup: viewers
+`
+	if err := os.WriteFile(userPath, []byte(user), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := filepath.Join(tmpDir, "repo")
+	nested := filepath.Join(repo, "deploy", "prod")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	project := `profile: finance
+regions: [KR]
+layout: files
+readOnlyGroup: finance:viewers
+`
+	if err := os.WriteFile(filepath.Join(repo, ProjectFileName), []byte(project), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFrom(userPath, nested)
+	if err != nil {
+		t.Fatalf("LoadFrom() error = %v", err)
+	}
+
+	if cfg.Profile != "finance" {
+		t.Errorf("Profile = %v, want finance", cfg.Profile)
+	}
+	if len(cfg.Regions) != 1 || cfg.Regions[0] != "KR" {
+		t.Errorf("Regions = %v, want [KR]", cfg.Regions)
+	}
+	if cfg.NameTemplate != "{{.Name}}" {
+		t.Errorf("NameTemplate = %v, want user value", cfg.NameTemplate)
+	}
+	if cfg.Output != "json" {
+		t.Errorf("Output = %v, want json", cfg.Output)
+	}
+	if want := filepath.Join(tmpDir, "kube", "nks.yaml"); cfg.Kubeconfig != want {
+		t.Errorf("Kubeconfig = %v, want %v", cfg.Kubeconfig, want)
+	}
+	if want := filepath.Join(tmpDir, "bin", "ncp-iam-authenticator"); cfg.AuthenticatorPath != want {
+		t.Errorf("AuthenticatorPath = %v, want %v", cfg.AuthenticatorPath, want)
+	}
+	if cfg.Layout != LayoutFiles {
+		t.Errorf("Layout = %v, want files", cfg.Layout)
+	}
+	if cfg.ReadOnlyGroup != "finance:viewers" || cfg.ReadOnlyUser != "" {
+		t.Errorf("ReadOnlyGroup, ReadOnlyUser = %v, %v", cfg.ReadOnlyGroup, cfg.ReadOnlyUser)
+	}
+	if len(cfg.Sources) != 2 {
+		t.Errorf("Sources = %v, want user and project files", cfg.Sources)
+	}
+}
+
+func TestLoadFrom_ProjectCannotSetPaths(t *testing.T) {
+	tmpDir := t.TempDir()
+	userPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(userPath, []byte("authenticatorPath: /usr/local/bin/ncp-iam-authenticator\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, project := range []string{
+		"authenticatorPath: ./evil\n",
+		"kubeconfig: kube/config\n",
+	} {
+		repo := t.TempDir()
+		if err := os.WriteFile(filepath.Join(repo, ProjectFileName), []byte(project), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if cfg, err := LoadFrom(userPath, repo); err == nil {
+			t.Errorf("LoadFrom() with project %q = %+v, want error", project, cfg)
+		}
+	}
+}
+
+func TestLoadFrom_MissingFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg, err := LoadFrom(filepath.Join(tmpDir, "missing.yaml"), tmpDir)
+	if err != nil {
+		t.Fatalf("LoadFrom() error = %v", err)
+	}
+	if cfg.Profile != "" || len(cfg.Sources) != 0 {
+		t.Errorf("LoadFrom() = %+v, want empty config", cfg)
+	}
+}
+
+func TestLoadFrom_UnknownField(t *testing.T) {
+	tmpDir := t.TempDir()
+	userPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(userPath, []byte("profle: typo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadFrom(userPath, ""); err == nil {
+		t.Error("LoadFrom() expected error for unknown field")
+	}
+}
+
+func TestLoadFrom_InvalidLayout(t *testing.T) {
+	tmpDir := t.TempDir()
+	userPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(userPath, []byte("layout: split\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadFrom(userPath, ""); err == nil {
+		t.Error("LoadFrom() expected error for unknown layout")
+	}
+}
+
+func TestLoadFrom_Guards(t *testing.T) {
+	tmpDir := t.TempDir()
+	userPath := filepath.Join(tmpDir, "config.yaml")
+	user := `guards:
+  - name: "prod-*"
+    revertAfter: 30m
+  - profile: finance
+    region: KR
+`
+	if err := os.WriteFile(userPath, []byte(user), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, ProjectFileName), []byte("guards:\n  - name: staging\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFrom(userPath, tmpDir)
+	if err != nil {
+		t.Fatalf("LoadFrom() error = %v", err)
+	}
+	if len(cfg.Guards) != 3 {
+		t.Fatalf("Guards = %+v, want user and project guards", cfg.Guards)
+	}
+
+	tests := []struct {
+		cluster, context, profile, region string
+		want                              string
+	}{
+		{"prod-api", "prod-api", "DEFAULT", "SGN", "name=prod-*"},
+		{"api", "prod-api", "DEFAULT", "KR", "name=prod-*"},
+		{"api", "api-user@api", "DEFAULT", "KR", ""},
+		{"api", "finance-api", "finance", "kr", "profile=finance,region=KR"},
+		{"api", "finance-api", "finance", "SGN", ""},
+		{"staging", "staging", "DEFAULT", "KR", "name=staging"},
+	}
+	for _, tt := range tests {
+		got := ""
+		if g := cfg.Guard(tt.cluster, tt.context, tt.profile, tt.region); g != nil {
+			got = g.String()
+		}
+		if got != tt.want {
+			t.Errorf("Guard(%s, %s, %s, %s) = %q, want %q", tt.cluster, tt.context, tt.profile, tt.region, got, tt.want)
+		}
+	}
+	if d := cfg.Guards[0].Revert(); d != 30*time.Minute {
+		t.Errorf("Revert() = %v, want 30m", d)
+	}
+}
+
+func TestLoadFrom_InvalidGuard(t *testing.T) {
+	for _, guard := range []string{`name: "prod-["`, `revertAfter: soon`, `revertAfter: -5m`} {
+		userPath := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(userPath, []byte("guards:\n  - "+guard+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadFrom(userPath, ""); err == nil {
+			t.Errorf("LoadFrom() expected error for guard %s", guard)
+		}
+	}
+}
+
+func TestLoadFrom_Prompt(t *testing.T) {
+	tmpDir := t.TempDir()
+	userPath := filepath.Join(tmpDir, "config.yaml")
+	user := `prompt:
+  format: "{{.Profile}}:{{.Name}}"
+  colors:
+    - name: "prod-*"
+      color: red
+    - profile: dev
+      color: green
+`
+	if err := os.WriteFile(userPath, []byte(user), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFrom(userPath, "")
+	if err != nil {
+		t.Fatalf("LoadFrom() error = %v", err)
+	}
+	if cfg.Prompt.Format != "{{.Profile}}:{{.Name}}" {
+		t.Errorf("Prompt.Format = %q", cfg.Prompt.Format)
+	}
+	tests := []struct {
+		cluster, profile string
+		want             string
+	}{
+		{"prod-api", "dev", "red"},
+		{"api", "dev", "green"},
+		{"api", "finance", ""},
+	}
+	for _, tt := range tests {
+		if got := cfg.PromptColor(tt.cluster, tt.cluster, tt.profile, "KR"); got != tt.want {
+			t.Errorf("PromptColor(%s, %s) = %q, want %q", tt.cluster, tt.profile, got, tt.want)
+		}
+	}
+}
+
+func TestLoadFrom_InvalidPrompt(t *testing.T) {
+	for _, prompt := range []string{`format: "{{.Name"`, "colors:\n    - name: prod\n      color: purple"} {
+		userPath := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(userPath, []byte("prompt:\n  "+prompt+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadFrom(userPath, ""); err == nil {
+			t.Errorf("LoadFrom() expected error for prompt %s", prompt)
+		}
+	}
+}
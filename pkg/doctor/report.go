@@ -0,0 +1,106 @@
+// Package doctor collects the results of environment checks and renders
+// them as a table or as JSON for support tickets.
+package doctor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"text/tabwriter"
+	"time"
+)
+
+// Status is the outcome of a single check.
+type Status string
+
+const (
+	Pass Status = "pass"
+	Warn Status = "warn"
+	Fail Status = "fail"
+	Skip Status = "skip"
+)
+
+// Result is one row of the report. Fix tells the user how to resolve a
+// warning or failure.
+type Result struct {
+	Check  string `json:"check"`
+	Status Status `json:"status"`
+	Detail string `json:"detail"`
+	Fix    string `json:"fix,omitempty"`
+}
+
+// Report is an ordered list of check results.
+type Report struct {
+	Generated time.Time `json:"generated"`
+	Platform  string    `json:"platform"`
+	Results   []Result  `json:"results"`
+}
+
+// NewReport returns an empty report stamped with the current time and platform.
+func NewReport() *Report {
+	return &Report{
+		Generated: time.Now().UTC(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		Results:   []Result{},
+	}
+}
+
+// Pass records a successful check.
+func (r *Report) Pass(check, detail string) {
+	r.add(check, Pass, detail, "")
+}
+
+// Warn records a check that works but needs attention.
+func (r *Report) Warn(check, detail, fix string) {
+	r.add(check, Warn, detail, fix)
+}
+
+// Fail records a check that will break nks-ctx or kubectl.
+func (r *Report) Fail(check, detail, fix string) {
+	r.add(check, Fail, detail, fix)
+}
+
+// Skip records a check that could not run because an earlier one failed.
+func (r *Report) Skip(check, detail string) {
+	r.add(check, Skip, detail, "")
+}
+
+func (r *Report) add(check string, status Status, detail, fix string) {
+	r.Results = append(r.Results, Result{Check: check, Status: status, Detail: detail, Fix: fix})
+}
+
+// Count returns the number of results with the given status.
+func (r *Report) Count(status Status) int {
+	n := 0
+	for _, res := range r.Results {
+		if res.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteText renders the report as a table, each fix on the line below its check.
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tCHECK\tDETAIL")
+	for _, res := range r.Results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", res.Status, res.Check, res.Detail)
+		if res.Fix != "" {
+			fmt.Fprintf(tw, "\t\tfix: %s\n", res.Fix)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d passed, %d warning(s), %d failed\n", r.Count(Pass), r.Count(Warn), r.Count(Fail))
+	return err
+}
+
+// WriteJSON renders the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
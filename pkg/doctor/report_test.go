@@ -0,0 +1,62 @@
+package doctor
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestReport_WriteText(t *testing.T) {
+	r := NewReport()
+	r.Pass("credentials", "from profile")
+	r.Fail("authenticator", "not found", "install ncp-iam-authenticator")
+	r.Warn("configure", "mode 0644", "chmod 600 ~/.ncloud/configure")
+
+	var b bytes.Buffer
+	if err := r.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+
+	for _, want := range []string{"STATUS", "pass", "credentials", "fix: install ncp-iam-authenticator", "1 passed, 1 warning(s), 1 failed"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "not found") > strings.Index(out, "fix: install") {
+		t.Errorf("fix should follow its check:\n%s", out)
+	}
+}
+
+func TestReport_WriteJSON(t *testing.T) {
+	r := NewReport()
+	r.Pass("credentials", "from env")
+	r.Skip("endpoints", "no credentials")
+
+	var b bytes.Buffer
+	if err := r.WriteJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	var got Report
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(got.Results) != 2 || got.Results[1].Status != Skip || got.Platform == "" {
+		t.Errorf("round trip = %+v", got)
+	}
+	if strings.Contains(b.String(), `"fix"`) {
+		t.Errorf("empty fix should be omitted:\n%s", b.String())
+	}
+}
+
+func TestReport_Count(t *testing.T) {
+	r := NewReport()
+	r.Fail("a", "", "")
+	r.Fail("b", "", "")
+	r.Pass("c", "")
+	if r.Count(Fail) != 2 || r.Count(Pass) != 1 || r.Count(Warn) != 0 {
+		t.Errorf("Count() = fail %d pass %d warn %d", r.Count(Fail), r.Count(Pass), r.Count(Warn))
+	}
+}
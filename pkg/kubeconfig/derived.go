@@ -0,0 +1,59 @@
+package kubeconfig
+
+import (
+	"fmt"
+	"sort"
+
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// DerivedName returns the default name of a cluster's derived context for
+// namespace, e.g. "prod-kr/payments".
+func DerivedName(clusterName, namespace string) string {
+	return clusterName + "/" + namespace
+}
+
+// IsDerived reports whether a context was created with CreateDerived.
+func (m *Manager) IsDerived(contextName string) bool {
+	meta := m.Meta(contextName)
+	return meta != nil && meta.Derived
+}
+
+// CreateDerived adds a context named name that uses the cluster and user
+// entries of the synced context parent with its own namespace. It is tagged
+// with the parent's metadata, so prune and refresh treat it with the parent.
+// Call Save to persist the change.
+func (m *Manager) CreateDerived(parent, name, namespace string) error {
+	p, ok := m.config.Contexts[parent]
+	if !ok {
+		return fmt.Errorf("context '%s' not found in kubeconfig", parent)
+	}
+	meta := m.Meta(parent)
+	if meta == nil {
+		return fmt.Errorf("context '%s' is not managed by nks-ctx", parent)
+	}
+	if meta.Derived {
+		return fmt.Errorf("context '%s' is itself derived", parent)
+	}
+	if _, exists := m.config.Contexts[name]; exists {
+		return fmt.Errorf("context '%s' already exists in kubeconfig", name)
+	}
+
+	m.config.Contexts[name] = &api.Context{Cluster: p.Cluster, AuthInfo: p.AuthInfo, Namespace: namespace}
+	derived := *meta
+	derived.Derived = true
+	return m.SetMeta(name, derived)
+}
+
+// DerivedContexts returns the sorted names of the derived contexts of a
+// cluster UUID.
+func (m *Manager) DerivedContexts(uuid string) []string {
+	var names []string
+	for ctxName := range m.config.Contexts {
+		if meta := m.Meta(ctxName); meta != nil && meta.Derived && meta.ClusterUUID == uuid {
+			names = append(names, ctxName)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,104 @@
+package kubeconfig
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestManager_CreateDerived(t *testing.T) {
+	manager := managerWithContexts(t, map[string]string{
+		"prod-kr": "prod-kr",
+		"other":   "other",
+	})
+	manager.config.AuthInfos["prod-kr-user"].Exec = &api.ExecConfig{
+		Command: "ncp-iam-authenticator",
+		Args:    []string{"token", "--clusterUuid", "u1", "--region", "KR"},
+	}
+	meta := ClusterMeta{ClusterName: "prod-kr", ClusterUUID: "u1", Region: "KR", Profile: "finance"}
+	if err := manager.SetMeta("prod-kr", meta); err != nil {
+		t.Fatal(err)
+	}
+
+	name := DerivedName("prod-kr", "payments")
+	if err := manager.CreateDerived("prod-kr", name, "payments"); err != nil {
+		t.Fatalf("CreateDerived() error = %v", err)
+	}
+	if err := manager.Save(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := reloaded.config.Contexts[name]
+	if ctx == nil || ctx.Cluster != "prod-kr" || ctx.AuthInfo != "prod-kr-user" || ctx.Namespace != "payments" {
+		t.Fatalf("derived context = %+v", ctx)
+	}
+	want := meta
+	want.Derived = true
+	if got := reloaded.Meta(name); got == nil || *got != want {
+		t.Errorf("Meta() = %+v, want %+v", got, want)
+	}
+	if !reloaded.IsDerived(name) || reloaded.IsDerived("prod-kr") {
+		t.Error("IsDerived() does not tell the derived context from its parent")
+	}
+	if got := reloaded.DerivedContexts("u1"); !reflect.DeepEqual(got, []string{name}) {
+		t.Errorf("DerivedContexts() = %v", got)
+	}
+
+	// Lookups used by sync must keep finding the parent.
+	if got := reloaded.FindContextByClusterUUID("u1"); got != "prod-kr" {
+		t.Errorf("FindContextByClusterUUID() = %q, want prod-kr", got)
+	}
+	if got := reloaded.FindContextByCluster("prod-kr"); got != "prod-kr" {
+		t.Errorf("FindContextByCluster() = %q, want prod-kr", got)
+	}
+	if got := reloaded.ContextsForCluster("u1"); !reflect.DeepEqual(got, []string{"prod-kr"}) {
+		t.Errorf("ContextsForCluster() = %v", got)
+	}
+	if got, _ := reloaded.FindContext("prod"); got != "prod-kr" {
+		t.Errorf("FindContext(prod) = %q, want prod-kr", got)
+	}
+
+	// Removing the derived context keeps the entries the parent uses.
+	if err := reloaded.RemoveContext(name); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reloaded.config.AuthInfos["prod-kr-user"]; !ok {
+		t.Error("RemoveContext() of derived context removed the parent's user")
+	}
+}
+
+func TestManager_CreateDerived_Errors(t *testing.T) {
+	manager := managerWithContexts(t, map[string]string{
+		"prod-kr":   "prod-kr",
+		"unmanaged": "unmanaged",
+	})
+	if err := manager.SetMeta("prod-kr", ClusterMeta{ClusterName: "prod-kr", ClusterUUID: "u1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.CreateDerived("prod-kr", "prod-kr/a", "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		parent string
+		ctx    string
+	}{
+		{"missing parent", "missing", "x"},
+		{"unmanaged parent", "unmanaged", "x"},
+		{"derived parent", "prod-kr/a", "x"},
+		{"name taken", "prod-kr", "prod-kr/a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := manager.CreateDerived(tt.parent, tt.ctx, "ns"); err == nil {
+				t.Error("CreateDerived() expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package kubeconfig
+
+import (
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Marshal returns the kubeconfig as it would be written by Save.
+func (m *Manager) Marshal() ([]byte, error) {
+	return clientcmd.Write(*m.config)
+}
+
+// Diff returns a unified diff between two renderings of the kubeconfig, or
+// "" if they are equal.
+func (m *Manager) Diff(before, after []byte) string {
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(before)),
+		B:        difflib.SplitLines(string(after)),
+		FromFile: m.path,
+		ToFile:   m.path,
+		Context:  3,
+	})
+	return diff
+}
@@ -0,0 +1,143 @@
+package kubeconfig
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/consol-lee/nks-ctx/pkg/selector"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// ExportOptions controls how Export rewrites exec users for another machine.
+type ExportOptions struct {
+	// Pin, if set, pins every exported exec user to it. Otherwise profile
+	// pinning is removed, so the users take credentials from whatever the
+	// receiving machine provides (NCLOUD_* keys or its default profile).
+	Pin *ExecPin
+	// Local keeps exec commands and pinning unchanged, for a file used on
+	// this machine only. Pin is ignored.
+	Local bool
+}
+
+// Labels returns the selector labels of a context: its name, the NKS cluster
+// name, and the region and profile recorded in its nks-ctx metadata.
+func (m *Manager) Labels(contextName string) map[string]string {
+	labels := map[string]string{"context": contextName}
+	if ctx, ok := m.config.Contexts[contextName]; ok {
+		labels["name"] = ctx.Cluster
+	}
+	if meta := m.Meta(contextName); meta != nil {
+		labels["name"] = meta.ClusterName
+		labels["region"] = meta.Region
+		labels["profile"] = meta.Profile
+	}
+	return labels
+}
+
+// StatusFunc returns the status of a cluster, for selectors that use the
+// status label.
+type StatusFunc func(profile, clusterUUID string) string
+
+// Select returns the sorted names of contexts whose labels match s. status
+// supplies the status label of contexts managed by nks-ctx and is only called
+// when s uses it; nil leaves the label empty.
+func (m *Manager) Select(s selector.Selector, status StatusFunc) []string {
+	var names []string
+	for name := range m.config.Contexts {
+		labels := m.Labels(name)
+		if meta := m.Meta(name); meta != nil && status != nil && s.Uses("status") {
+			labels["status"] = status(meta.Profile, meta.ClusterUUID)
+		}
+		if s.Matches(labels) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Export returns a standalone kubeconfig holding only contextNames and the
+// cluster and user entries they refer to, with certificate and key files
+// embedded and current-context set to the first name. Unless opts.Local is
+// set, exec commands are reduced to their base name so they resolve through
+// PATH wherever the file is used. The returned Manager has no path; write it
+// with Marshal.
+func (m *Manager) Export(contextNames []string, opts ExportOptions) (*Manager, error) {
+	if len(contextNames) == 0 {
+		return nil, fmt.Errorf("no contexts to export")
+	}
+
+	config := api.NewConfig()
+	for _, name := range contextNames {
+		ctx, ok := m.config.Contexts[name]
+		if !ok {
+			return nil, fmt.Errorf("context '%s' not found in kubeconfig", name)
+		}
+		cluster, ok := m.config.Clusters[ctx.Cluster]
+		if !ok {
+			return nil, fmt.Errorf("cluster '%s' of context '%s' not found in kubeconfig", ctx.Cluster, name)
+		}
+		user, ok := m.config.AuthInfos[ctx.AuthInfo]
+		if !ok {
+			return nil, fmt.Errorf("user '%s' of context '%s' not found in kubeconfig", ctx.AuthInfo, name)
+		}
+		config.Contexts[name] = ctx.DeepCopy()
+		config.Clusters[ctx.Cluster] = cluster.DeepCopy()
+		config.AuthInfos[ctx.AuthInfo] = user.DeepCopy()
+	}
+	config.CurrentContext = contextNames[0]
+
+	if err := api.FlattenConfig(config); err != nil {
+		return nil, fmt.Errorf("failed to embed certificate files: %w", err)
+	}
+
+	out := &Manager{config: config}
+	for _, name := range contextNames {
+		e := out.ExecConfig(name)
+		if e == nil || opts.Local {
+			continue
+		}
+		e.Command = filepath.Base(e.Command)
+		if opts.Pin != nil {
+			out.PinExec(name, *opts.Pin)
+		} else {
+			unpinExec(e)
+		}
+
+		if meta := out.Meta(name); meta != nil {
+			meta.Profile = ""
+			if opts.Pin != nil {
+				meta.Profile = opts.Pin.Profile
+			}
+			if err := out.SetMeta(name, *meta); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return out, nil
+}
+
+// unpinExec undoes PinExec's profile pinning: the --profile arg, the
+// NKS_CTX_PROFILE variable and the blanked credential variables.
+func unpinExec(e *api.ExecConfig) {
+	if v, i := argValue(e.Args, "--profile"); i >= 0 {
+		end := i + 1
+		if v != "" {
+			end++
+		}
+		e.Args = append(append([]string{}, e.Args[:i]...), e.Args[end:]...)
+	}
+
+	drop := map[string]bool{PinnedProfileEnv: true}
+	for _, name := range pinnedKeyEnv {
+		drop[name] = true
+	}
+	var env []api.ExecEnvVar
+	for _, v := range e.Env {
+		if !drop[v.Name] {
+			env = append(env, v)
+		}
+	}
+	e.Env = env
+}
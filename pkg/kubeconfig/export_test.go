@@ -0,0 +1,140 @@
+package kubeconfig
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/consol-lee/nks-ctx/pkg/selector"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func pinnedManager(t *testing.T) *Manager {
+	t.Helper()
+	manager := managerWithContexts(t, map[string]string{"prod": "prod-api", "dev": "dev-api", "other": "other"})
+	for name, region := range map[string]string{"prod": "KR", "dev": "SGN"} {
+		manager.config.AuthInfos[name+"-user"].Exec = &api.ExecConfig{
+			Command: "/opt/homebrew/bin/ncp-iam-authenticator",
+			Args:    []string{"token", "--clusterUuid", name + "-uuid", "--region", region},
+		}
+		manager.PinExec(name, ExecPin{Profile: "finance", APIGateway: "https://fin.example.com"})
+		meta := ClusterMeta{ClusterName: name + "-api", ClusterUUID: name + "-uuid", Region: region, Profile: "finance"}
+		if err := manager.SetMeta(name, meta); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return manager
+}
+
+func TestManager_Export(t *testing.T) {
+	manager := pinnedManager(t)
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	if err := os.WriteFile(caFile, []byte("test-ca"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	manager.config.Clusters["prod-api"].CertificateAuthority = caFile
+
+	out, err := manager.Export([]string{"prod"}, ExportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := out.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.CurrentContext != "prod" || len(config.Contexts) != 1 || len(config.Clusters) != 1 || len(config.AuthInfos) != 1 {
+		t.Fatalf("exported config = %+v", config)
+	}
+	if cluster := config.Clusters["prod-api"]; cluster.CertificateAuthority != "" || string(cluster.CertificateAuthorityData) != "test-ca" {
+		t.Errorf("CA not embedded: %+v", cluster)
+	}
+
+	e := config.AuthInfos["prod-user"].Exec
+	if e.Command != "ncp-iam-authenticator" {
+		t.Errorf("Command = %q, want base name", e.Command)
+	}
+	if want := []string{"token", "--clusterUuid", "prod-uuid", "--region", "KR"}; !reflect.DeepEqual(e.Args, want) {
+		t.Errorf("Args = %v, want %v", e.Args, want)
+	}
+	if want := []api.ExecEnvVar{{Name: "NCLOUD_API_GW", Value: "https://fin.example.com"}}; !reflect.DeepEqual(e.Env, want) {
+		t.Errorf("Env = %v, want %v", e.Env, want)
+	}
+	if meta := out.Meta("prod"); meta == nil || meta.Profile != "" || meta.ClusterUUID != "prod-uuid" {
+		t.Errorf("Meta = %+v", meta)
+	}
+
+	if e := manager.ExecConfig("prod"); e.Command != "/opt/homebrew/bin/ncp-iam-authenticator" || len(e.Env) == 1 {
+		t.Error("Export() modified the source kubeconfig")
+	}
+}
+
+func TestManager_Export_Pin(t *testing.T) {
+	manager := pinnedManager(t)
+	out, err := manager.Export([]string{"dev", "prod"}, ExportOptions{Pin: &ExecPin{Profile: "ci"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.GetCurrentContext() != "dev" {
+		t.Errorf("current-context = %q, want first exported", out.GetCurrentContext())
+	}
+	e := out.ExecConfig("prod")
+	if v, _ := argValue(e.Args, "--profile"); v != "ci" {
+		t.Errorf("Args = %v, want --profile ci", e.Args)
+	}
+	if meta := out.Meta("prod"); meta.Profile != "ci" {
+		t.Errorf("Meta.Profile = %q, want ci", meta.Profile)
+	}
+
+	local, err := manager.Export([]string{"prod"}, ExportOptions{Local: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := local.ExecConfig("prod"), manager.ExecConfig("prod"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Local export exec = %+v, want unchanged %+v", got, want)
+	}
+
+	if _, err := manager.Export([]string{"missing"}, ExportOptions{}); err == nil {
+		t.Error("Export() of missing context should fail")
+	}
+}
+
+func TestManager_Select(t *testing.T) {
+	manager := pinnedManager(t)
+	tests := map[string][]string{
+		"region=KR":           {"prod"},
+		"profile=finance":     {"dev", "prod"},
+		"name=~.*-api":        {"dev", "prod"},
+		"name=other":          {"other"},
+		"context!~prod|other": {"dev"},
+	}
+	for expr, want := range tests {
+		s, err := selector.Parse(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := manager.Select(s, nil); !reflect.DeepEqual(got, want) {
+			t.Errorf("Select(%q) = %v, want %v", expr, got, want)
+		}
+	}
+
+	s, _ := selector.Parse("status=RUNNING")
+	status := func(profile, uuid string) string {
+		if profile == "finance" && uuid == "dev-uuid" {
+			return "RUNNING"
+		}
+		return "CREATING"
+	}
+	if got := manager.Select(s, status); !reflect.DeepEqual(got, []string{"dev"}) {
+		t.Errorf("Select(status=RUNNING) = %v, want [dev]", got)
+	}
+	if labels := manager.Labels("prod"); labels["region"] != "KR" || labels["context"] != "prod" {
+		t.Errorf("Labels() = %v", labels)
+	}
+}
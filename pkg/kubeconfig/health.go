@@ -0,0 +1,36 @@
+package kubeconfig
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// Problems reports why a context would not work: dangling cluster or user
+// references, a missing server, or an exec command that cannot be found.
+// It returns nil for a healthy context.
+func (m *Manager) Problems(contextName string) []string {
+	ctx, ok := m.config.Contexts[contextName]
+	if !ok {
+		return []string{fmt.Sprintf("context '%s' not found", contextName)}
+	}
+
+	var problems []string
+	if cluster, ok := m.config.Clusters[ctx.Cluster]; !ok {
+		problems = append(problems, fmt.Sprintf("cluster entry '%s' is missing", ctx.Cluster))
+	} else if cluster.Server == "" {
+		problems = append(problems, fmt.Sprintf("cluster entry '%s' has no server", ctx.Cluster))
+	}
+
+	user, ok := m.config.AuthInfos[ctx.AuthInfo]
+	switch {
+	case !ok:
+		problems = append(problems, fmt.Sprintf("user entry '%s' is missing", ctx.AuthInfo))
+	case user.Exec == nil:
+		problems = append(problems, fmt.Sprintf("user entry '%s' has no exec credential plugin", ctx.AuthInfo))
+	default:
+		if _, err := exec.LookPath(user.Exec.Command); err != nil {
+			problems = append(problems, fmt.Sprintf("exec command '%s' not found", user.Exec.Command))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,41 @@
+package kubeconfig
+
+import (
+	"os"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestManager_Problems(t *testing.T) {
+	manager := managerWithContexts(t, map[string]string{
+		"healthy":  "cluster-a",
+		"no-exec":  "cluster-b",
+		"bad-exec": "cluster-c",
+	})
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager.config.AuthInfos["healthy-user"].Exec = &api.ExecConfig{Command: exe}
+	manager.config.AuthInfos["bad-exec-user"].Exec = &api.ExecConfig{Command: "/nonexistent/ncp-iam-authenticator"}
+	manager.config.Contexts["dangling"] = &api.Context{Cluster: "gone", AuthInfo: "gone-user"}
+
+	tests := []struct {
+		context string
+		want    int
+	}{
+		{"healthy", 0},
+		{"no-exec", 1},
+		{"bad-exec", 1},
+		{"dangling", 2},
+		{"missing", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.context, func(t *testing.T) {
+			if got := manager.Problems(tt.context); len(got) != tt.want {
+				t.Errorf("Problems(%s) = %v, want %d problem(s)", tt.context, got, tt.want)
+			}
+		})
+	}
+}
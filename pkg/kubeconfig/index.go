@@ -0,0 +1,147 @@
+package kubeconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/consol-lee/nks-ctx/pkg/selector"
+)
+
+// IndexFileName is the index of per-cluster kubeconfig files in FilesDir.
+const IndexFileName = "index.json"
+
+// FilesDir returns the directory of per-cluster kubeconfig files (~/.kube/nks).
+func FilesDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".kube", "nks")
+}
+
+// ClusterFile returns the per-cluster kubeconfig path <dir>/<profile>/<name>.yaml.
+func ClusterFile(dir, profile, clusterName string) string {
+	if profile == "" {
+		profile = "DEFAULT"
+	}
+	return filepath.Join(dir, filepath.Base(profile), filepath.Base(clusterName)+".yaml")
+}
+
+// Index lists the per-cluster kubeconfig files synced in the files layout.
+type Index struct {
+	path    string
+	Entries []IndexEntry `json:"entries"`
+}
+
+// IndexEntry is one per-cluster kubeconfig file.
+type IndexEntry struct {
+	ClusterMeta
+	Context string `json:"context"`
+	File    string `json:"file"`
+}
+
+// Labels returns the selector labels of the entry, as Manager.Labels does for contexts.
+func (e IndexEntry) Labels() map[string]string {
+	return map[string]string{
+		"name":    e.ClusterName,
+		"context": e.Context,
+		"region":  e.Region,
+		"profile": e.Profile,
+	}
+}
+
+// LoadIndex reads the index in dir. A missing index yields an empty one.
+func LoadIndex(dir string) (*Index, error) {
+	path := filepath.Join(dir, IndexFileName)
+	idx := &Index{path: path}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return idx, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, idx); err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig index %s: %w", path, err)
+	}
+	return idx, nil
+}
+
+// Path returns the index file.
+func (x *Index) Path() string {
+	return x.path
+}
+
+// Save writes the index, sorted by profile and cluster name.
+func (x *Index) Save() error {
+	sort.Slice(x.Entries, func(i, j int) bool {
+		a, b := x.Entries[i], x.Entries[j]
+		if a.Profile != b.Profile {
+			return a.Profile < b.Profile
+		}
+		return a.ClusterName < b.ClusterName
+	})
+	raw, err := json.MarshalIndent(x, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(x.path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(x.path, append(raw, '\n'), 0600)
+}
+
+// Put adds e, replacing the entry for the same profile and cluster UUID.
+func (x *Index) Put(e IndexEntry) {
+	for i := range x.Entries {
+		if x.Entries[i].Profile == e.Profile && x.Entries[i].ClusterUUID == e.ClusterUUID {
+			x.Entries[i] = e
+			return
+		}
+	}
+	x.Entries = append(x.Entries, e)
+}
+
+// Remove drops the entry for file.
+func (x *Index) Remove(file string) {
+	entries := x.Entries[:0]
+	for _, e := range x.Entries {
+		if e.File != file {
+			entries = append(entries, e)
+		}
+	}
+	x.Entries = entries
+}
+
+// Select returns the entries whose labels match s. status supplies the
+// status label and is only called when s uses it; nil leaves it empty.
+func (x *Index) Select(s selector.Selector, status StatusFunc) []IndexEntry {
+	var entries []IndexEntry
+	for _, e := range x.Entries {
+		labels := e.Labels()
+		if status != nil && s.Uses("status") {
+			labels["status"] = status(e.Profile, e.ClusterUUID)
+		}
+		if s.Matches(labels) {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Find returns the entry whose context or cluster name is name, or else the
+// first whose context contains it, like Manager.FindContext.
+func (x *Index) Find(name string) (*IndexEntry, error) {
+	for i, e := range x.Entries {
+		if e.Context == name || e.ClusterName == name {
+			return &x.Entries[i], nil
+		}
+	}
+	for i, e := range x.Entries {
+		if strings.Contains(e.Context, name) {
+			return &x.Entries[i], nil
+		}
+	}
+	return nil, fmt.Errorf("context not found: %s", name)
+}
@@ -0,0 +1,75 @@
+package kubeconfig
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/consol-lee/nks-ctx/pkg/selector"
+)
+
+func TestIndex_SaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	idx, err := LoadIndex(dir)
+	if err != nil {
+		t.Fatalf("LoadIndex() of missing index error = %v", err)
+	}
+
+	prod := IndexEntry{
+		ClusterMeta: ClusterMeta{ClusterName: "prod", ClusterUUID: "u1", Region: "KR", Profile: "finance"},
+		Context:     "nks-prod",
+		File:        ClusterFile(dir, "finance", "prod"),
+	}
+	dev := IndexEntry{
+		ClusterMeta: ClusterMeta{ClusterName: "dev", ClusterUUID: "u2", Region: "SGN", Profile: "DEFAULT"},
+		Context:     "dev-user@dev",
+		File:        ClusterFile(dir, "", "dev"),
+	}
+	idx.Put(prod)
+	idx.Put(dev)
+	prod.Context = "renamed"
+	idx.Put(prod)
+	if err := idx.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Entries) != 2 || loaded.Entries[0].ClusterName != "dev" || loaded.Entries[1].Context != "renamed" {
+		t.Fatalf("Entries = %+v", loaded.Entries)
+	}
+	if want := filepath.Join(dir, "DEFAULT", "dev.yaml"); loaded.Entries[0].File != want {
+		t.Errorf("File = %v, want %v", loaded.Entries[0].File, want)
+	}
+
+	if e, err := loaded.Find("prod"); err != nil || e.Context != "renamed" {
+		t.Errorf("Find(prod) = %+v, %v", e, err)
+	}
+	if e, err := loaded.Find("user@"); err != nil || e.ClusterName != "dev" {
+		t.Errorf("Find(user@) = %+v, %v", e, err)
+	}
+	if _, err := loaded.Find("missing"); err == nil {
+		t.Error("Find(missing) expected error")
+	}
+
+	s, _ := selector.Parse("region=KR")
+	if got := loaded.Select(s, nil); len(got) != 1 || got[0].ClusterName != "prod" {
+		t.Errorf("Select(region=KR) = %+v", got)
+	}
+	s, _ = selector.Parse("status=RUNNING")
+	status := func(profile, uuid string) string {
+		if uuid == "u2" {
+			return "RUNNING"
+		}
+		return ""
+	}
+	if got := loaded.Select(s, status); len(got) != 1 || got[0].ClusterName != "dev" {
+		t.Errorf("Select(status=RUNNING) = %+v", got)
+	}
+
+	loaded.Remove(prod.File)
+	if len(loaded.Entries) != 1 {
+		t.Errorf("Remove() left %+v", loaded.Entries)
+	}
+}
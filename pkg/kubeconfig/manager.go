@@ -14,13 +14,17 @@ import (
 type Manager struct {
 	path   string
 	config *api.Config
+	// merged is set when config combines several files; see NewManagerForPaths.
+	merged bool
 }
 
-// DefaultPath returns the default kubeconfig path (~/.kube/config),
-// or the value of KUBECONFIG if set.
+// DefaultPath returns the default kubeconfig path (~/.kube/config), or the
+// first file listed in KUBECONFIG if set.
 func DefaultPath() string {
-	if p := os.Getenv("KUBECONFIG"); p != "" {
-		return p
+	for _, p := range filepath.SplitList(os.Getenv("KUBECONFIG")) {
+		if p != "" {
+			return p
+		}
 	}
 	home, _ := os.UserHomeDir()
 	return filepath.Join(home, ".kube", "config")
@@ -28,8 +32,11 @@ func DefaultPath() string {
 
 // NewManager loads the kubeconfig from the default path.
 func NewManager() (*Manager, error) {
-	path := DefaultPath()
+	return NewManagerForPath(DefaultPath())
+}
 
+// NewManagerForPath loads the kubeconfig at path. A missing file yields an empty config.
+func NewManagerForPath(path string) (*Manager, error) {
 	config, err := clientcmd.LoadFromFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -42,6 +49,36 @@ func NewManager() (*Manager, error) {
 	return &Manager{path: path, config: config}, nil
 }
 
+// NewManagerForPaths merges several kubeconfig files the way kubectl merges a
+// KUBECONFIG list: the first file to define an entry wins, and missing files
+// are skipped. SwitchContext writes current-context to the first path; Save
+// is refused because the merged entries belong to different files.
+func NewManagerForPaths(paths []string) (*Manager, error) {
+	if len(paths) == 1 {
+		return NewManagerForPath(paths[0])
+	}
+	rules := &clientcmd.ClientConfigLoadingRules{Precedence: paths}
+	config, err := rules.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	return &Manager{path: paths[0], config: config, merged: true}, nil
+}
+
+// Path returns the kubeconfig file the Manager reads and writes.
+func (m *Manager) Path() string {
+	return m.path
+}
+
+// Origin returns the file that defines a context: the file it was read from
+// when kubeconfig is merged from several files, otherwise Path.
+func (m *Manager) Origin(contextName string) string {
+	if ctx, ok := m.config.Contexts[contextName]; ok && m.merged && ctx.LocationOfOrigin != "" {
+		return ctx.LocationOfOrigin
+	}
+	return m.path
+}
+
 // GetCurrentContext returns the name of the currently active context.
 func (m *Manager) GetCurrentContext() string {
 	return m.config.CurrentContext
@@ -54,19 +91,36 @@ func (m *Manager) FindContext(name string) (string, error) {
 		return name, nil
 	}
 
-	// Partial match
+	// Partial match, preferring synced contexts over derived ones
+	var derived string
 	for ctxName := range m.config.Contexts {
-		if strings.Contains(ctxName, name) {
+		if !strings.Contains(ctxName, name) {
+			continue
+		}
+		if !m.IsDerived(ctxName) {
 			return ctxName, nil
 		}
+		derived = ctxName
+	}
+	if derived != "" {
+		return derived, nil
 	}
 
 	return "", fmt.Errorf("context not found: %s", name)
 }
 
 // FindContextByCluster returns the context name associated with a cluster name.
+// Contexts tagged with nks-ctx metadata are matched first, so renamed contexts are found.
 func (m *Manager) FindContextByCluster(clusterName string) string {
+	for _, ctxName := range m.ManagedContexts() {
+		if meta := m.Meta(ctxName); meta.ClusterName == clusterName && !meta.Derived {
+			return ctxName
+		}
+	}
 	for ctxName, ctx := range m.config.Contexts {
+		if m.IsDerived(ctxName) {
+			continue
+		}
 		if ctx.Cluster == clusterName || strings.Contains(ctxName, clusterName) {
 			return ctxName
 		}
@@ -74,6 +128,24 @@ func (m *Manager) FindContextByCluster(clusterName string) string {
 	return ""
 }
 
+// FindContextByClusterUUID returns the context whose exec user authenticates
+// with --clusterUuid uuid, as written by ncp-iam-authenticator. Derived
+// contexts, which share that user, are skipped.
+func (m *Manager) FindContextByClusterUUID(uuid string) string {
+	for ctxName := range m.config.Contexts {
+		exec := m.ExecConfig(ctxName)
+		if exec == nil || m.IsDerived(ctxName) {
+			continue
+		}
+		for i := 0; i+1 < len(exec.Args); i++ {
+			if exec.Args[i] == "--clusterUuid" && exec.Args[i+1] == uuid {
+				return ctxName
+			}
+		}
+	}
+	return ""
+}
+
 // SwitchContext sets the current-context and writes kubeconfig to disk.
 func (m *Manager) SwitchContext(contextName string) error {
 	if _, ok := m.config.Contexts[contextName]; !ok {
@@ -81,7 +153,49 @@ func (m *Manager) SwitchContext(contextName string) error {
 	}
 
 	m.config.CurrentContext = contextName
+	if !m.merged {
+		return clientcmd.WriteToFile(*m.config, m.path)
+	}
+
+	// Only current-context changes; the context itself lives in another file.
+	primary, err := NewManagerForPath(m.path)
+	if err != nil {
+		return err
+	}
+	primary.config.CurrentContext = contextName
+	return primary.Save()
+}
 
+// SetCurrentContext sets current-context without writing kubeconfig. It
+// reports whether the value changed; call Save to persist.
+func (m *Manager) SetCurrentContext(contextName string) bool {
+	if m.config.CurrentContext == contextName {
+		return false
+	}
+	m.config.CurrentContext = contextName
+	return true
+}
+
+// ExecConfig returns the exec credential plugin config of the user bound to
+// contextName, or nil if the user does not use an exec plugin. Changes made
+// through the returned pointer are persisted by Save.
+func (m *Manager) ExecConfig(contextName string) *api.ExecConfig {
+	ctx, ok := m.config.Contexts[contextName]
+	if !ok {
+		return nil
+	}
+	user, ok := m.config.AuthInfos[ctx.AuthInfo]
+	if !ok {
+		return nil
+	}
+	return user.Exec
+}
+
+// Save writes kubeconfig to disk.
+func (m *Manager) Save() error {
+	if m.merged {
+		return fmt.Errorf("kubeconfig merged from several files cannot be saved to %s", m.path)
+	}
 	return clientcmd.WriteToFile(*m.config, m.path)
 }
 
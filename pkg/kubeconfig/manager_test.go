@@ -140,6 +140,38 @@ func TestManager_SwitchContext_NotFound(t *testing.T) {
 	}
 }
 
+func TestNewManagerForPaths_SwitchWritesPrimary(t *testing.T) {
+	cluster := managerWithContexts(t, map[string]string{"nks-a": "cluster-a"})
+	primary := filepath.Join(t.TempDir(), "config")
+	t.Setenv("KUBECONFIG", primary+string(filepath.ListSeparator)+cluster.Path())
+
+	if got := DefaultPath(); got != primary {
+		t.Errorf("DefaultPath() = %v, want first KUBECONFIG entry", got)
+	}
+
+	merged, err := NewManagerForPaths([]string{primary, cluster.Path()})
+	if err != nil {
+		t.Fatalf("NewManagerForPaths() error = %v", err)
+	}
+	if err := merged.SwitchContext("nks-a"); err != nil {
+		t.Fatalf("SwitchContext() error = %v", err)
+	}
+	if err := merged.Save(); err == nil {
+		t.Error("Save() of merged kubeconfig should fail")
+	}
+
+	written, err := NewManagerForPath(primary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written.GetCurrentContext() != "nks-a" || len(written.ListContextNames()) != 0 {
+		t.Errorf("primary = %+v, want only current-context", written.config)
+	}
+	if reloaded, _ := NewManagerForPath(cluster.Path()); len(reloaded.ListContextNames()) != 1 {
+		t.Error("cluster file was modified")
+	}
+}
+
 func TestManager_ListContextNames(t *testing.T) {
 	manager := managerWithContexts(t, map[string]string{
 		"ctx-1": "cluster-1",
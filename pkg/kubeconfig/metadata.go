@@ -0,0 +1,99 @@
+package kubeconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ExtensionName is the kubeconfig context extension that marks entries managed by nks-ctx.
+const ExtensionName = "nks-ctx"
+
+// ClusterMeta identifies the NKS cluster behind a managed context.
+// It is stored as a context extension so it survives context renames.
+type ClusterMeta struct {
+	ClusterName string `json:"clusterName"`
+	ClusterUUID string `json:"clusterUuid"`
+	Region      string `json:"region"`
+	Profile     string `json:"profile,omitempty"`
+	// Derived marks a context created with 'context create' from the
+	// cluster's synced context, whose cluster and user entries it reuses.
+	Derived bool `json:"derived,omitempty"`
+	// ReadOnly marks a derived context whose user impersonates a view-only
+	// identity. Its user entry is a copy of the synced context's user.
+	ReadOnly bool `json:"readOnly,omitempty"`
+}
+
+// Meta returns the nks-ctx metadata of a context, or nil if it is not managed.
+func (m *Manager) Meta(contextName string) *ClusterMeta {
+	ctx, ok := m.config.Contexts[contextName]
+	if !ok {
+		return nil
+	}
+	ext, ok := ctx.Extensions[ExtensionName]
+	if !ok {
+		return nil
+	}
+	unknown, ok := ext.(*runtime.Unknown)
+	if !ok {
+		return nil
+	}
+	var meta ClusterMeta
+	if err := json.Unmarshal(unknown.Raw, &meta); err != nil {
+		return nil
+	}
+	return &meta
+}
+
+// SetMeta tags a context as managed by nks-ctx. Call Save to persist the change.
+func (m *Manager) SetMeta(contextName string, meta ClusterMeta) error {
+	ctx, ok := m.config.Contexts[contextName]
+	if !ok {
+		return fmt.Errorf("context '%s' not found in kubeconfig", contextName)
+	}
+	raw, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	if ctx.Extensions == nil {
+		ctx.Extensions = make(map[string]runtime.Object)
+	}
+	ctx.Extensions[ExtensionName] = &runtime.Unknown{Raw: raw, ContentType: runtime.ContentTypeJSON}
+	return nil
+}
+
+// ManagedContexts returns the sorted names of contexts tagged by nks-ctx,
+// including derived contexts.
+func (m *Manager) ManagedContexts() []string {
+	var names []string
+	for name := range m.config.Contexts {
+		if m.Meta(name) != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RenameContext renames a context, keeping current-context pointed at it.
+// Call Save to persist the change.
+func (m *Manager) RenameContext(oldName, newName string) error {
+	if oldName == newName {
+		return nil
+	}
+	ctx, ok := m.config.Contexts[oldName]
+	if !ok {
+		return fmt.Errorf("context '%s' not found in kubeconfig", oldName)
+	}
+	if _, exists := m.config.Contexts[newName]; exists {
+		return fmt.Errorf("context '%s' already exists in kubeconfig", newName)
+	}
+	delete(m.config.Contexts, oldName)
+	m.config.Contexts[newName] = ctx
+	if m.config.CurrentContext == oldName {
+		m.config.CurrentContext = newName
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package kubeconfig
+
+import (
+	"testing"
+)
+
+func TestManager_MetaRoundTrip(t *testing.T) {
+	manager := managerWithContexts(t, map[string]string{
+		"nks_kr_prod_1234": "nks_kr_prod_1234",
+		"other":            "other-cluster",
+	})
+
+	meta := ClusterMeta{ClusterName: "prod", ClusterUUID: "1234", Region: "KR", Profile: "finance"}
+	if err := manager.SetMeta("nks_kr_prod_1234", meta); err != nil {
+		t.Fatalf("SetMeta() error = %v", err)
+	}
+	if err := manager.RenameContext("nks_kr_prod_1234", "finance-prod"); err != nil {
+		t.Fatalf("RenameContext() error = %v", err)
+	}
+	if err := manager.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	reloaded, err := NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := reloaded.Meta("finance-prod")
+	if got == nil || *got != meta {
+		t.Fatalf("Meta() after reload = %+v, want %+v", got, meta)
+	}
+	if reloaded.Meta("other") != nil {
+		t.Error("Meta(other) should be nil for unmanaged context")
+	}
+	if ctx := reloaded.FindContextByCluster("prod"); ctx != "finance-prod" {
+		t.Errorf("FindContextByCluster(prod) = %v, want finance-prod", ctx)
+	}
+	if names := reloaded.ManagedContexts(); len(names) != 1 || names[0] != "finance-prod" {
+		t.Errorf("ManagedContexts() = %v, want [finance-prod]", names)
+	}
+}
+
+func TestManager_RenameContext_Conflict(t *testing.T) {
+	manager := managerWithContexts(t, map[string]string{
+		"ctx-a": "cluster-a",
+		"ctx-b": "cluster-b",
+	})
+
+	if err := manager.RenameContext("ctx-a", "ctx-b"); err == nil {
+		t.Error("RenameContext() expected error when target exists")
+	}
+	if err := manager.RenameContext("missing", "ctx-c"); err == nil {
+		t.Error("RenameContext() expected error for missing context")
+	}
+}
@@ -0,0 +1,192 @@
+package kubeconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// NamespaceCacheTTL is how long listed namespaces are reused, so completion
+// does not call the API server on every keystroke.
+const NamespaceCacheTTL = time.Minute
+
+// Namespace returns the namespace set on a context, or "".
+func (m *Manager) Namespace(contextName string) string {
+	if ctx, ok := m.config.Contexts[contextName]; ok {
+		return ctx.Namespace
+	}
+	return ""
+}
+
+// SetNamespace sets a context's namespace and writes it to the kubeconfig
+// file that defines the context.
+func (m *Manager) SetNamespace(contextName, namespace string) error {
+	ctx, ok := m.config.Contexts[contextName]
+	if !ok {
+		return fmt.Errorf("context '%s' not found in kubeconfig", contextName)
+	}
+	ctx.Namespace = namespace
+	if !m.merged {
+		return m.Save()
+	}
+
+	origin := m.Origin(contextName)
+	owner, err := NewManagerForPath(origin)
+	if err != nil {
+		return err
+	}
+	if _, ok := owner.config.Contexts[contextName]; !ok {
+		return fmt.Errorf("context '%s' not found in %s", contextName, origin)
+	}
+	owner.config.Contexts[contextName].Namespace = namespace
+	return owner.Save()
+}
+
+// Server returns the API server URL of a context's cluster, or "".
+func (m *Manager) Server(contextName string) string {
+	ctx, ok := m.config.Contexts[contextName]
+	if !ok {
+		return ""
+	}
+	if cluster, ok := m.config.Clusters[ctx.Cluster]; ok {
+		return cluster.Server
+	}
+	return ""
+}
+
+// RESTConfig returns a client config for a context, authenticating the way
+// kubectl would (including exec credential plugins).
+func (m *Manager) RESTConfig(contextName string) (*rest.Config, error) {
+	return clientcmd.NewNonInteractiveClientConfig(*m.config, contextName, &clientcmd.ConfigOverrides{}, nil).ClientConfig()
+}
+
+// apiCodecs decode only the API types nks-ctx reads and writes. The client-go
+// clientset registers every API group when the binary starts, which would
+// double the start time of every command, prompt and token included; this
+// scheme is built on first use instead.
+var apiCodecs = sync.OnceValue(func() serializer.CodecFactory {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(corev1.AddToScheme(scheme))
+	utilruntime.Must(authorizationv1.AddToScheme(scheme))
+	return serializer.NewCodecFactory(scheme)
+})
+
+// restClient returns a client for an API group version of a context's
+// cluster. Its requests decode into typed objects, and API server errors are
+// *apierrors.StatusError.
+func (m *Manager) restClient(contextName string, gv schema.GroupVersion) (*rest.RESTClient, error) {
+	cfg, err := m.RESTConfig(contextName)
+	if err != nil {
+		return nil, err
+	}
+	cfg.GroupVersion = &gv
+	cfg.APIPath = "/apis"
+	if gv.Group == "" {
+		cfg.APIPath = "/api"
+	}
+	cfg.NegotiatedSerializer = apiCodecs().WithoutConversion()
+	return rest.RESTClientFor(cfg)
+}
+
+// ListNamespaces lists the namespaces of a context's cluster, sorted. API
+// server errors are wrapped, so apierrors.IsForbidden and similar apply.
+func (m *Manager) ListNamespaces(ctx context.Context, contextName string) ([]string, error) {
+	client, err := m.restClient(contextName, corev1.SchemeGroupVersion)
+	if err != nil {
+		return nil, err
+	}
+	list := &corev1.NamespaceList{}
+	if err := client.Get().Resource("namespaces").Do(ctx).Into(list); err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	names := make([]string, 0, len(list.Items))
+	for _, item := range list.Items {
+		names = append(names, item.Name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// NamespaceCache keeps recently listed namespaces per API server.
+type NamespaceCache struct {
+	path string
+
+	mu      sync.Mutex
+	Servers map[string]NamespaceEntry `json:"servers"`
+}
+
+// NamespaceEntry is the namespace list of one API server.
+type NamespaceEntry struct {
+	Namespaces []string  `json:"namespaces"`
+	Fetched    time.Time `json:"fetched"`
+}
+
+// DefaultNamespaceCachePath returns the namespace cache file path.
+func DefaultNamespaceCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "nks-ctx", "namespaces.json")
+}
+
+// LoadNamespaceCache reads the cache at path. Missing or unreadable files
+// yield an empty cache.
+func LoadNamespaceCache(path string) *NamespaceCache {
+	c := &NamespaceCache{path: path, Servers: make(map[string]NamespaceEntry)}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return c
+	}
+	if err := json.Unmarshal(raw, c); err != nil || c.Servers == nil {
+		c.Servers = make(map[string]NamespaceEntry)
+	}
+	return c
+}
+
+// Get returns the namespaces of server if they were listed within ttl.
+func (c *NamespaceCache) Get(server string, ttl time.Duration) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.Servers[server]
+	if !ok || time.Since(e.Fetched) > ttl {
+		return nil, false
+	}
+	return e.Namespaces, true
+}
+
+// Put records the namespaces of server and writes the cache to disk.
+func (c *NamespaceCache) Put(server string, namespaces []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Servers[server] = NamespaceEntry{Namespaces: namespaces, Fetched: time.Now()}
+	for s, e := range c.Servers {
+		if time.Since(e.Fetched) > NamespaceCacheTTL {
+			delete(c.Servers, s)
+		}
+	}
+
+	raw, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, raw, 0600)
+}
@@ -0,0 +1,92 @@
+package kubeconfig
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestManager_ListNamespaces(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/api/v1/namespaces" || r.Header.Get("Authorization") != "Bearer t0ken" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","message":"namespaces is forbidden","reason":"Forbidden","code":403}`))
+			return
+		}
+		w.Write([]byte(`{"kind":"NamespaceList","apiVersion":"v1","items":[{"metadata":{"name":"kube-system"}},{"metadata":{"name":"default"}}]}`))
+	}))
+	defer server.Close()
+
+	manager := managerWithContexts(t, map[string]string{"dev": "dev-cluster"})
+	manager.config.Clusters["dev-cluster"].Server = server.URL
+	manager.config.Clusters["dev-cluster"].CertificateAuthorityData = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	manager.config.AuthInfos["dev-user"] = &api.AuthInfo{Token: "t0ken"}
+
+	got, err := manager.ListNamespaces(context.Background(), "dev")
+	if err != nil {
+		t.Fatalf("ListNamespaces() error = %v", err)
+	}
+	if want := []string{"default", "kube-system"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListNamespaces() = %v, want %v", got, want)
+	}
+
+	manager.config.AuthInfos["dev-user"].Token = "wrong"
+	if _, err := manager.ListNamespaces(context.Background(), "dev"); !apierrors.IsForbidden(err) || err.Error() != "failed to list namespaces: namespaces is forbidden" {
+		t.Errorf("ListNamespaces() error = %v", err)
+	}
+}
+
+func TestManager_SetNamespace_Merged(t *testing.T) {
+	cluster := managerWithContexts(t, map[string]string{"nks-a": "cluster-a"})
+	primary := filepath.Join(t.TempDir(), "config")
+
+	merged, err := NewManagerForPaths([]string{primary, cluster.Path()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := merged.SetNamespace("nks-a", "payments"); err != nil {
+		t.Fatalf("SetNamespace() error = %v", err)
+	}
+	if merged.Namespace("nks-a") != "payments" {
+		t.Errorf("Namespace() = %q in memory", merged.Namespace("nks-a"))
+	}
+
+	reloaded, err := NewManagerForPath(cluster.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.Namespace("nks-a") != "payments" {
+		t.Errorf("namespace not written to the context's file")
+	}
+	if err := merged.SetNamespace("missing", "x"); err == nil {
+		t.Error("SetNamespace() of missing context should fail")
+	}
+}
+
+func TestNamespaceCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "namespaces.json")
+	cache := LoadNamespaceCache(path)
+	if _, ok := cache.Get("https://a", time.Minute); ok {
+		t.Error("Get() on empty cache = ok")
+	}
+	if err := cache.Put("https://a", []string{"default"}); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := LoadNamespaceCache(path)
+	if got, ok := reloaded.Get("https://a", time.Minute); !ok || !reflect.DeepEqual(got, []string{"default"}) {
+		t.Errorf("Get() = %v, %v", got, ok)
+	}
+	if _, ok := reloaded.Get("https://a", 0); ok {
+		t.Error("Get() with expired ttl = ok")
+	}
+}
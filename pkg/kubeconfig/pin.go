@@ -0,0 +1,79 @@
+package kubeconfig
+
+import (
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// PinnedProfileEnv is set in the exec env of users pinned to a profile.
+// `nks-ctx token` resolves credentials from that profile only, ignoring
+// NCLOUD_* keys in the caller's environment.
+const PinnedProfileEnv = "NKS_CTX_PROFILE"
+
+// pinnedKeyEnv are cleared in pinned users so that keys exported in the
+// caller's shell cannot override the pinned profile.
+var pinnedKeyEnv = []string{"NCLOUD_ACCESS_KEY", "NCLOUD_SECRET_KEY", "NCLOUD_SESSION_TOKEN"}
+
+// ExecPin is the account a managed exec user must authenticate against.
+type ExecPin struct {
+	// Profile is written as --profile and NKS_CTX_PROFILE. Leave it empty for
+	// credentials that only exist in the environment.
+	Profile string
+	// APIGateway is written as NCLOUD_API_GW.
+	APIGateway string
+	// Region is written as --region.
+	Region string
+}
+
+// PinExec records pin in the exec user of contextName, so kubectl obtains
+// tokens for the same account regardless of the caller's NCLOUD_* variables.
+// It reports whether the user changed; call Save to persist.
+func (m *Manager) PinExec(contextName string, pin ExecPin) bool {
+	e := m.ExecConfig(contextName)
+	if e == nil {
+		return false
+	}
+
+	before := e.DeepCopy()
+	if pin.Region != "" {
+		e.Args = setArg(e.Args, "--region", pin.Region)
+	}
+	if pin.APIGateway != "" {
+		e.Env = setEnv(e.Env, "NCLOUD_API_GW", pin.APIGateway)
+	}
+	if pin.Profile != "" {
+		e.Args = setArg(e.Args, "--profile", pin.Profile)
+		e.Env = setEnv(e.Env, PinnedProfileEnv, pin.Profile)
+		for _, name := range pinnedKeyEnv {
+			e.Env = setEnv(e.Env, name, "")
+		}
+	}
+	return !execEqual(before, e)
+}
+
+// setEnv sets name in an exec env list, appending it if absent.
+func setEnv(env []api.ExecEnvVar, name, value string) []api.ExecEnvVar {
+	for i := range env {
+		if env[i].Name == name {
+			env[i].Value = value
+			return env
+		}
+	}
+	return append(env, api.ExecEnvVar{Name: name, Value: value})
+}
+
+func execEqual(a, b *api.ExecConfig) bool {
+	if len(a.Args) != len(b.Args) || len(a.Env) != len(b.Env) {
+		return false
+	}
+	for i := range a.Args {
+		if a.Args[i] != b.Args[i] {
+			return false
+		}
+	}
+	for i := range a.Env {
+		if a.Env[i] != b.Env[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package kubeconfig
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestManager_PinExec(t *testing.T) {
+	manager := managerWithContexts(t, map[string]string{"prod": "prod-cluster", "plain": "plain-cluster"})
+	manager.config.AuthInfos["prod-user"].Exec = &api.ExecConfig{
+		Command: "ncp-iam-authenticator",
+		Args:    []string{"token", "--clusterUuid", "u1", "--region", "KR"},
+		Env:     []api.ExecEnvVar{{Name: "NCLOUD_API_GW", Value: "https://old.example.com"}},
+	}
+
+	pin := ExecPin{Profile: "finance", APIGateway: "https://fin-ncloud.apigw.fin-ntruss.com", Region: "FKR"}
+	if !manager.PinExec("prod", pin) {
+		t.Fatal("PinExec() = false, want change")
+	}
+
+	e := manager.ExecConfig("prod")
+	wantArgs := []string{"token", "--clusterUuid", "u1", "--region", "FKR", "--profile", "finance"}
+	if !reflect.DeepEqual(e.Args, wantArgs) {
+		t.Errorf("Args = %v, want %v", e.Args, wantArgs)
+	}
+	env := make(map[string]string)
+	for _, v := range e.Env {
+		env[v.Name] = v.Value
+	}
+	if env["NCLOUD_API_GW"] != pin.APIGateway || env[PinnedProfileEnv] != "finance" {
+		t.Errorf("Env = %v", e.Env)
+	}
+	if v, ok := env["NCLOUD_ACCESS_KEY"]; !ok || v != "" {
+		t.Errorf("NCLOUD_ACCESS_KEY should be pinned empty, Env = %v", e.Env)
+	}
+
+	if manager.PinExec("prod", pin) {
+		t.Error("PinExec() again = true, want no change")
+	}
+	if manager.PinExec("plain", pin) {
+		t.Error("PinExec() on user without exec = true")
+	}
+}
+
+func TestManager_PinExec_EnvCredentials(t *testing.T) {
+	manager := managerWithContexts(t, map[string]string{"dev": "dev-cluster"})
+	manager.config.AuthInfos["dev-user"].Exec = &api.ExecConfig{Command: "ncp-iam-authenticator", Args: []string{"token"}}
+
+	manager.PinExec("dev", ExecPin{APIGateway: "https://ncloud.apigw.ntruss.com", Region: "KR"})
+
+	for _, v := range manager.ExecConfig("dev").Env {
+		if v.Name == "NCLOUD_ACCESS_KEY" || v.Name == PinnedProfileEnv {
+			t.Errorf("env credentials must not be cleared or pinned to a profile: %v", v)
+		}
+	}
+}
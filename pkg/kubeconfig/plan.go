@@ -0,0 +1,130 @@
+package kubeconfig
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// PlannedServer stands in for the API server URL of entries planned by
+// AddPlanned; the real value is only known once ncp-iam-authenticator runs.
+const PlannedServer = "https://<assigned-by-ncp-iam-authenticator>"
+
+// Change is one entry of a kubeconfig change plan.
+type Change struct {
+	Kind   string `json:"kind"`   // "cluster", "user", "context" or "current-context"
+	Name   string `json:"name"`   // entry name
+	Action string `json:"action"` // "add", "change" or "remove"
+}
+
+// Clone returns an in-memory copy of the Manager. Changes to the copy do not
+// affect m until the copy is saved.
+func (m *Manager) Clone() *Manager {
+	return &Manager{path: m.path, config: m.config.DeepCopy(), merged: m.merged}
+}
+
+// Changes lists the cluster, user and context entries that differ between
+// from and m, sorted by kind and name.
+func (m *Manager) Changes(from *Manager) []Change {
+	var changes []Change
+	changes = append(changes, diffEntries("cluster", from.config.Clusters, m.config.Clusters)...)
+	changes = append(changes, diffEntries("user", from.config.AuthInfos, m.config.AuthInfos)...)
+	changes = append(changes, diffEntries("context", from.config.Contexts, m.config.Contexts)...)
+	if from.config.CurrentContext != m.config.CurrentContext {
+		changes = append(changes, Change{Kind: "current-context", Name: m.config.CurrentContext, Action: "change"})
+	}
+	return changes
+}
+
+func diffEntries[T any](kind string, before, after map[string]T) []Change {
+	var changes []Change
+	for name, b := range before {
+		a, ok := after[name]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Kind: kind, Name: name, Action: "remove"})
+		case !reflect.DeepEqual(a, b):
+			changes = append(changes, Change{Kind: kind, Name: name, Action: "change"})
+		}
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			changes = append(changes, Change{Kind: kind, Name: name, Action: "add"})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
+
+// AddPlanned adds the cluster, user and context entries ncp-iam-authenticator
+// would write for a cluster, without running it. The server is PlannedServer
+// and the user runs command with the authenticator's token args. It returns
+// the context name.
+func (m *Manager) AddPlanned(clusterName, clusterUUID, region, command string) string {
+	userName := clusterName + "-user"
+	ctxName := userName + "@" + clusterName
+
+	m.config.Clusters[clusterName] = &api.Cluster{Server: PlannedServer}
+	m.config.AuthInfos[userName] = &api.AuthInfo{
+		Exec: &api.ExecConfig{
+			APIVersion:      "client.authentication.k8s.io/v1beta1",
+			Command:         command,
+			Args:            []string{"token", "--clusterUuid", clusterUUID, "--region", region},
+			InteractiveMode: api.IfAvailableExecInteractiveMode,
+		},
+	}
+	m.config.Contexts[ctxName] = &api.Context{Cluster: clusterName, AuthInfo: userName}
+	return ctxName
+}
+
+// RemoveContext deletes a context, and its cluster and user entries if no
+// other context refers to them. current-context is cleared if it pointed at
+// the removed context. Call Save to persist the change.
+func (m *Manager) RemoveContext(contextName string) error {
+	ctx, ok := m.config.Contexts[contextName]
+	if !ok {
+		return fmt.Errorf("context '%s' not found in kubeconfig", contextName)
+	}
+	delete(m.config.Contexts, contextName)
+
+	clusterUsed, userUsed := false, false
+	for _, other := range m.config.Contexts {
+		clusterUsed = clusterUsed || other.Cluster == ctx.Cluster
+		userUsed = userUsed || other.AuthInfo == ctx.AuthInfo
+	}
+	if !clusterUsed {
+		delete(m.config.Clusters, ctx.Cluster)
+	}
+	if !userUsed {
+		delete(m.config.AuthInfos, ctx.AuthInfo)
+	}
+	if m.config.CurrentContext == contextName {
+		m.config.CurrentContext = ""
+	}
+	return nil
+}
+
+// ContextsForCluster returns the sorted names of all synced contexts for a
+// cluster UUID, whether tagged with nks-ctx metadata or found through exec
+// args. Derived contexts are not included.
+func (m *Manager) ContextsForCluster(uuid string) []string {
+	var names []string
+	for ctxName := range m.config.Contexts {
+		if m.IsDerived(ctxName) {
+			continue
+		}
+		if meta := m.Meta(ctxName); meta != nil && meta.ClusterUUID == uuid {
+			names = append(names, ctxName)
+			continue
+		}
+		if e := m.ExecConfig(ctxName); e != nil {
+			if v, _ := argValue(e.Args, "--clusterUuid"); v == uuid {
+				names = append(names, ctxName)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,76 @@
+package kubeconfig
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestManager_Changes(t *testing.T) {
+	manager := managerWithContexts(t, map[string]string{
+		"keep":   "keep-cluster",
+		"gone":   "gone-cluster",
+		"tagged": "tagged-cluster",
+	})
+	manager.config.CurrentContext = "gone"
+	original := manager.Clone()
+
+	ctxName := manager.AddPlanned("new", "uuid-new", "KR", "/usr/bin/ncp-iam-authenticator")
+	if err := manager.RemoveContext("gone"); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.SetMeta("tagged", ClusterMeta{ClusterName: "tagged", ClusterUUID: "u"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Change{
+		{Kind: "cluster", Name: "gone-cluster", Action: "remove"},
+		{Kind: "cluster", Name: "new", Action: "add"},
+		{Kind: "user", Name: "gone-user", Action: "remove"},
+		{Kind: "user", Name: "new-user", Action: "add"},
+		{Kind: "context", Name: "gone", Action: "remove"},
+		{Kind: "context", Name: ctxName, Action: "add"},
+		{Kind: "context", Name: "tagged", Action: "change"},
+		{Kind: "current-context", Name: "", Action: "change"},
+	}
+	if got := manager.Changes(original); !reflect.DeepEqual(got, want) {
+		t.Errorf("Changes() =\n%v\nwant\n%v", got, want)
+	}
+
+	if _, ok := original.config.Contexts["gone"]; !ok {
+		t.Error("Clone() shares state with the original")
+	}
+	if got := manager.Changes(manager.Clone()); len(got) != 0 {
+		t.Errorf("Changes() of identical configs = %v", got)
+	}
+}
+
+func TestManager_RemoveContext_KeepsSharedEntries(t *testing.T) {
+	manager := managerWithContexts(t, map[string]string{"a": "shared"})
+	manager.config.Contexts["b"] = manager.config.Contexts["a"].DeepCopy()
+
+	if err := manager.RemoveContext("a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := manager.config.Clusters["shared"]; !ok {
+		t.Error("cluster still used by context b was removed")
+	}
+	if _, ok := manager.config.AuthInfos["a-user"]; !ok {
+		t.Error("user still used by context b was removed")
+	}
+	if err := manager.RemoveContext("a"); err == nil {
+		t.Error("RemoveContext() of missing context should fail")
+	}
+}
+
+func TestManager_ContextsForCluster(t *testing.T) {
+	manager := managerWithContexts(t, map[string]string{"renamed": "c1"})
+	if err := manager.SetMeta("renamed", ClusterMeta{ClusterName: "c1", ClusterUUID: "uuid-1"}); err != nil {
+		t.Fatal(err)
+	}
+	planned := manager.AddPlanned("c1", "uuid-1", "KR", "ncp-iam-authenticator")
+
+	want := []string{planned, "renamed"}
+	if got := manager.ContextsForCluster("uuid-1"); !reflect.DeepEqual(got, want) {
+		t.Errorf("ContextsForCluster() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,124 @@
+package kubeconfig
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+)
+
+// ReadOnlyName returns the name of a cluster's read-only context, e.g.
+// "prod-kr:read-only".
+func ReadOnlyName(clusterName string) string {
+	return clusterName + ":read-only"
+}
+
+// IsReadOnly reports whether a context was created with CreateReadOnly.
+func (m *Manager) IsReadOnly(contextName string) bool {
+	meta := m.Meta(contextName)
+	return meta != nil && meta.ReadOnly
+}
+
+// CreateReadOnly adds a derived context named name for the cluster of the
+// synced context parent. Its user is a copy of the parent's user that
+// impersonates user and groups (kubectl --as and --as-group), so requests are
+// authorized as that identity. Call Save to persist the change.
+func (m *Manager) CreateReadOnly(parent, name, user string, groups []string) error {
+	userName := name + "-user"
+	if _, exists := m.config.AuthInfos[userName]; exists {
+		return fmt.Errorf("user '%s' already exists in kubeconfig", userName)
+	}
+	if err := m.CreateDerived(parent, name, m.Namespace(parent)); err != nil {
+		return err
+	}
+
+	ro := m.config.AuthInfos[m.config.Contexts[parent].AuthInfo].DeepCopy()
+	ro.Impersonate = user
+	ro.ImpersonateGroups = groups
+	m.config.AuthInfos[userName] = ro
+	m.config.Contexts[name].AuthInfo = userName
+
+	meta := *m.Meta(name)
+	meta.ReadOnly = true
+	return m.SetMeta(name, meta)
+}
+
+// Impersonation returns the user and groups a context's user impersonates.
+func (m *Manager) Impersonation(contextName string) (string, []string) {
+	ctx, ok := m.config.Contexts[contextName]
+	if !ok {
+		return "", nil
+	}
+	user, ok := m.config.AuthInfos[ctx.AuthInfo]
+	if !ok {
+		return "", nil
+	}
+	return user.Impersonate, user.ImpersonateGroups
+}
+
+// RefreshReadOnly copies the exec credential plugin of the synced context of
+// a read-only context's cluster into its user again, keeping the
+// impersonation, after sync has changed the original. It reports whether
+// anything changed; call Save to persist.
+func (m *Manager) RefreshReadOnly(contextName string) bool {
+	meta := m.Meta(contextName)
+	if meta == nil || !meta.ReadOnly {
+		return false
+	}
+	parents := m.ContextsForCluster(meta.ClusterUUID)
+	if len(parents) == 0 {
+		return false
+	}
+	from := m.config.AuthInfos[m.config.Contexts[parents[0]].AuthInfo]
+	to := m.config.AuthInfos[m.config.Contexts[contextName].AuthInfo]
+	if from == nil || to == nil || reflect.DeepEqual(from.Exec, to.Exec) {
+		return false
+	}
+	to.Exec = from.Exec.DeepCopy()
+	return true
+}
+
+// AccessDeniedError reports a SelfSubjectAccessReview that was not allowed.
+type AccessDeniedError struct {
+	Verb     string
+	Resource string
+	Name     string
+	Reason   string
+}
+
+func (e *AccessDeniedError) Error() string {
+	msg := fmt.Sprintf("not allowed to %s %s '%s'", e.Verb, e.Resource, e.Name)
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+// CheckImpersonation asks the API server, with a SelfSubjectAccessReview as
+// the context's own identity, whether it may impersonate user and groups. It
+// returns an *AccessDeniedError for the first one that is not allowed.
+func (m *Manager) CheckImpersonation(ctx context.Context, contextName, user string, groups []string) error {
+	checks := []struct{ resource, name string }{{"users", user}}
+	for _, g := range groups {
+		checks = append(checks, struct{ resource, name string }{"groups", g})
+	}
+	client, err := m.restClient(contextName, authorizationv1.SchemeGroupVersion)
+	if err != nil {
+		return err
+	}
+	for _, c := range checks {
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{Verb: "impersonate", Resource: c.resource, Name: c.name},
+			},
+		}
+		if err := client.Post().Resource("selfsubjectaccessreviews").Body(review).Do(ctx).Into(review); err != nil {
+			return fmt.Errorf("failed to review access: %w", err)
+		}
+		if !review.Status.Allowed {
+			return &AccessDeniedError{Verb: "impersonate", Resource: c.resource, Name: c.name, Reason: review.Status.Reason}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package kubeconfig
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestManager_CreateReadOnly(t *testing.T) {
+	manager := managerWithContexts(t, map[string]string{"prod-kr": "prod-kr"})
+	manager.config.AuthInfos["prod-kr-user"].Exec = &api.ExecConfig{
+		Command: "ncp-iam-authenticator",
+		Args:    []string{"token", "--clusterUuid", "u1", "--region", "KR"},
+	}
+	if err := manager.SetMeta("prod-kr", ClusterMeta{ClusterName: "prod-kr", ClusterUUID: "u1", Region: "KR"}); err != nil {
+		t.Fatal(err)
+	}
+
+	name := ReadOnlyName("prod-kr")
+	if err := manager.CreateReadOnly("prod-kr", name, "viewer", []string{"view"}); err != nil {
+		t.Fatalf("CreateReadOnly() error = %v", err)
+	}
+	if err := manager.Save(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reloaded.IsReadOnly(name) || !reloaded.IsDerived(name) || reloaded.IsReadOnly("prod-kr") {
+		t.Errorf("IsReadOnly/IsDerived of %s and its parent are wrong", name)
+	}
+	user, groups := reloaded.Impersonation(name)
+	if user != "viewer" || !reflect.DeepEqual(groups, []string{"view"}) {
+		t.Errorf("Impersonation() = %q, %v", user, groups)
+	}
+	if user, _ := reloaded.Impersonation("prod-kr"); user != "" {
+		t.Errorf("parent user impersonates %q", user)
+	}
+	if got := reloaded.FindContextByClusterUUID("u1"); got != "prod-kr" {
+		t.Errorf("FindContextByClusterUUID() = %q, want prod-kr", got)
+	}
+
+	// A sync that rewrites the parent's exec user is carried over.
+	reloaded.ExecConfig("prod-kr").Env = []api.ExecEnvVar{{Name: "NCLOUD_PROFILE", Value: "finance"}}
+	if !reloaded.RefreshReadOnly(name) {
+		t.Fatal("RefreshReadOnly() = false after the parent's user changed")
+	}
+	if e := reloaded.ExecConfig(name); len(e.Env) != 1 {
+		t.Errorf("read-only exec env = %v", e.Env)
+	}
+	if reloaded.RefreshReadOnly(name) {
+		t.Error("RefreshReadOnly() = true without changes")
+	}
+	if user, _ := reloaded.Impersonation(name); user != "viewer" {
+		t.Errorf("RefreshReadOnly() dropped impersonation, user = %q", user)
+	}
+
+	if err := reloaded.CreateReadOnly("prod-kr", name, "viewer", nil); err == nil {
+		t.Error("CreateReadOnly() twice should fail")
+	}
+}
+
+func TestManager_CheckImpersonation(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/apis/authorization.k8s.io/v1/selfsubjectaccessreviews" {
+			http.NotFound(w, r)
+			return
+		}
+		var review struct {
+			Spec struct {
+				ResourceAttributes map[string]string `json:"resourceAttributes"`
+			} `json:"spec"`
+		}
+		json.NewDecoder(r.Body).Decode(&review)
+		attrs := review.Spec.ResourceAttributes
+		allowed := attrs["verb"] == "impersonate" && attrs["name"] != "admins"
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"kind":       "SelfSubjectAccessReview",
+			"apiVersion": "authorization.k8s.io/v1",
+			"status":     map[string]interface{}{"allowed": allowed, "reason": "RBAC"},
+		})
+	}))
+	defer server.Close()
+
+	manager := managerWithContexts(t, map[string]string{"dev": "dev-cluster"})
+	manager.config.Clusters["dev-cluster"].Server = server.URL
+	manager.config.Clusters["dev-cluster"].CertificateAuthorityData = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	manager.config.AuthInfos["dev-user"] = &api.AuthInfo{Token: "t0ken"}
+
+	if err := manager.CheckImpersonation(context.Background(), "dev", "viewer", []string{"view"}); err != nil {
+		t.Errorf("CheckImpersonation() error = %v", err)
+	}
+
+	err := manager.CheckImpersonation(context.Background(), "dev", "viewer", []string{"view", "admins"})
+	var denied *AccessDeniedError
+	if !errors.As(err, &denied) || denied.Resource != "groups" || denied.Name != "admins" {
+		t.Errorf("CheckImpersonation() error = %v, want denial for group admins", err)
+	}
+}
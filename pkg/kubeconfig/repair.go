@@ -0,0 +1,151 @@
+package kubeconfig
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// authenticatorBinary is the command name ncp-iam-authenticator writes into exec users.
+const authenticatorBinary = "ncp-iam-authenticator"
+
+// RepairOptions supplies the current environment to RepairExecUsers.
+type RepairOptions struct {
+	// AuthenticatorPath replaces missing ncp-iam-authenticator commands.
+	AuthenticatorPath string
+	// TokenCommand replaces missing commands of users that run `nks-ctx token`.
+	TokenCommand string
+	// ProfileExists reports whether a --profile value can still be resolved.
+	ProfileExists func(profile string) bool
+}
+
+// Repair is the outcome of RepairExecUsers for one managed exec user.
+type Repair struct {
+	Context string
+	User    string
+	Fixed   []string // problems that were rewritten in memory
+	Unfixed []string // problems that need manual action
+}
+
+// RepairExecUsers checks the exec users of managed contexts for missing
+// commands, missing --clusterUuid/--region args and profiles that no longer
+// exist, and rewrites what it can from the context's nks-ctx metadata.
+// Changes are made in memory; call Save to persist them.
+func (m *Manager) RepairExecUsers(opts RepairOptions) []Repair {
+	var repairs []Repair
+	seen := make(map[string]bool)
+
+	for _, ctxName := range m.ManagedContexts() {
+		user := m.config.Contexts[ctxName].AuthInfo
+		if seen[user] {
+			continue
+		}
+		seen[user] = true
+
+		r := Repair{Context: ctxName, User: user}
+		e := m.ExecConfig(ctxName)
+		if e == nil {
+			r.Unfixed = append(r.Unfixed, "user has no exec credential plugin; run 'kubectl nks-ctx' to resync")
+		} else {
+			meta := m.Meta(ctxName)
+			r.repairCommand(e, opts)
+			r.repairArgs(e, meta)
+			r.repairProfile(e, meta, opts.ProfileExists)
+		}
+
+		if len(r.Fixed) > 0 || len(r.Unfixed) > 0 {
+			repairs = append(repairs, r)
+		}
+	}
+	return repairs
+}
+
+func (r *Repair) repairCommand(e *api.ExecConfig, opts RepairOptions) {
+	if _, err := exec.LookPath(e.Command); err == nil {
+		return
+	}
+
+	replacement := opts.TokenCommand
+	if filepath.Base(e.Command) == authenticatorBinary {
+		replacement = opts.AuthenticatorPath
+	}
+	if replacement == "" || replacement == e.Command {
+		r.Unfixed = append(r.Unfixed, fmt.Sprintf("command '%s' not found", e.Command))
+		return
+	}
+	if _, err := exec.LookPath(replacement); err != nil {
+		r.Unfixed = append(r.Unfixed, fmt.Sprintf("command '%s' not found and replacement '%s' is missing too", e.Command, replacement))
+		return
+	}
+	r.Fixed = append(r.Fixed, fmt.Sprintf("command '%s' not found, now '%s'", e.Command, replacement))
+	e.Command = replacement
+}
+
+func (r *Repair) repairArgs(e *api.ExecConfig, meta *ClusterMeta) {
+	required := []struct{ flag, value string }{
+		{"--clusterUuid", meta.ClusterUUID},
+		{"--region", meta.Region},
+	}
+	for _, req := range required {
+		if v, _ := argValue(e.Args, req.flag); v != "" {
+			continue
+		}
+		if req.value == "" {
+			r.Unfixed = append(r.Unfixed, fmt.Sprintf("%s is missing and not recorded in metadata", req.flag))
+			continue
+		}
+		e.Args = setArg(e.Args, req.flag, req.value)
+		r.Fixed = append(r.Fixed, fmt.Sprintf("%s was missing, now %s", req.flag, req.value))
+	}
+}
+
+func (r *Repair) repairProfile(e *api.ExecConfig, meta *ClusterMeta, exists func(string) bool) {
+	profile, _ := argValue(e.Args, "--profile")
+	if profile == "" || exists == nil || exists(profile) {
+		return
+	}
+	if meta.Profile != "" && meta.Profile != profile && exists(meta.Profile) {
+		e.Args = setArg(e.Args, "--profile", meta.Profile)
+		for _, env := range e.Env {
+			if env.Name == PinnedProfileEnv {
+				e.Env = setEnv(e.Env, PinnedProfileEnv, meta.Profile)
+			}
+		}
+		r.Fixed = append(r.Fixed, fmt.Sprintf("profile '%s' no longer exists, now '%s'", profile, meta.Profile))
+		return
+	}
+	r.Unfixed = append(r.Unfixed, fmt.Sprintf("profile '%s' no longer exists; add it back or resync with --profile", profile))
+}
+
+// argValue returns the value following flag in args and the flag's index.
+// The value is "" if flag is absent (index -1) or not followed by a value.
+func argValue(args []string, flag string) (string, int) {
+	for i := 0; i < len(args); i++ {
+		if args[i] != flag {
+			continue
+		}
+		if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+			return args[i+1], i
+		}
+		return "", i
+	}
+	return "", -1
+}
+
+// setArg sets flag's value in args, appending the flag if it is absent.
+func setArg(args []string, flag, value string) []string {
+	v, i := argValue(args, flag)
+	switch {
+	case i < 0:
+		return append(args, flag, value)
+	case v != "":
+		args[i+1] = value
+		return args
+	}
+	out := append([]string{}, args[:i+1]...)
+	out = append(out, value)
+	return append(out, args[i+1:]...)
+}
@@ -0,0 +1,102 @@
+package kubeconfig
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestManager_RepairExecUsers(t *testing.T) {
+	manager := managerWithContexts(t, map[string]string{
+		"stale":   "cluster-a",
+		"noargs":  "cluster-b",
+		"deadpro": "cluster-c",
+		"healthy": "cluster-d",
+	})
+
+	bin := filepath.Join(t.TempDir(), "ncp-iam-authenticator")
+	if err := os.WriteFile(bin, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	users := map[string]*api.ExecConfig{
+		"stale":   {Command: "/nonexistent/bin/ncp-iam-authenticator", Args: []string{"token", "--clusterUuid", "a", "--region", "KR"}},
+		"noargs":  {Command: bin, Args: []string{"token", "--clusterUuid", "--region", "KR"}},
+		"deadpro": {Command: bin, Args: []string{"token", "--clusterUuid", "c", "--region", "KR", "--profile", "gone"}},
+		"healthy": {Command: bin, Args: []string{"token", "--clusterUuid", "d", "--region", "KR"}},
+	}
+	for ctx, e := range users {
+		manager.config.AuthInfos[ctx+"-user"].Exec = e
+		if err := manager.SetMeta(ctx, ClusterMeta{ClusterName: ctx, ClusterUUID: ctx, Region: "KR", Profile: "finance"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	before, err := manager.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repairs := manager.RepairExecUsers(RepairOptions{
+		AuthenticatorPath: bin,
+		ProfileExists:     func(p string) bool { return p == "finance" },
+	})
+
+	got := make(map[string]Repair)
+	for _, r := range repairs {
+		got[r.Context] = r
+	}
+	if _, ok := got["healthy"]; ok || len(repairs) != 3 {
+		t.Fatalf("repairs = %+v, want stale, noargs and deadpro", repairs)
+	}
+	for ctx, r := range got {
+		if len(r.Fixed) != 1 || len(r.Unfixed) != 0 {
+			t.Errorf("%s: Fixed = %v, Unfixed = %v", ctx, r.Fixed, r.Unfixed)
+		}
+	}
+
+	if cmd := manager.ExecConfig("stale").Command; cmd != bin {
+		t.Errorf("stale command = %v, want %v", cmd, bin)
+	}
+	wantArgs := []string{"token", "--clusterUuid", "noargs", "--region", "KR"}
+	if args := manager.ExecConfig("noargs").Args; !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("noargs args = %v, want %v", args, wantArgs)
+	}
+	if v, _ := argValue(manager.ExecConfig("deadpro").Args, "--profile"); v != "finance" {
+		t.Errorf("deadpro profile = %v, want finance", v)
+	}
+
+	after, err := manager.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := manager.Diff(before, after)
+	if !strings.Contains(diff, "-      command: /nonexistent/bin/ncp-iam-authenticator") || !strings.Contains(diff, "+      command: "+bin) {
+		t.Errorf("diff missing command change:\n%s", diff)
+	}
+	if manager.Diff(after, after) != "" {
+		t.Error("Diff of identical input should be empty")
+	}
+}
+
+func TestManager_RepairExecUsers_Unfixable(t *testing.T) {
+	manager := managerWithContexts(t, map[string]string{"ctx": "cluster"})
+	manager.config.AuthInfos["ctx-user"].Exec = &api.ExecConfig{
+		Command: "/nonexistent/nks-ctx",
+		Args:    []string{"token", "--clusterUuid", "u", "--region", "KR", "--profile", "gone"},
+	}
+	if err := manager.SetMeta("ctx", ClusterMeta{ClusterName: "c", ClusterUUID: "u", Region: "KR"}); err != nil {
+		t.Fatal(err)
+	}
+
+	repairs := manager.RepairExecUsers(RepairOptions{
+		ProfileExists: func(string) bool { return false },
+	})
+	if len(repairs) != 1 || len(repairs[0].Unfixed) != 2 || len(repairs[0].Fixed) != 0 {
+		t.Fatalf("repairs = %+v, want two unfixed problems", repairs)
+	}
+}
@@ -29,9 +29,23 @@ func GenerateHMACSignature(method, uri, queryString, timestamp, accessKey, secre
 	return signature
 }
 
-// PrepareAuthHeaders prepares authentication headers for NCP API request
+// sessionTokenHeader carries the security token of temporary credentials.
+const sessionTokenHeader = "x-ncp-iam-security-token"
+
+// PrepareAuthHeaders prepares authentication headers for NCP API request.
+// Temporary credentials are refreshed first if they are about to expire.
 func (c *Client) PrepareAuthHeaders(method, uri, queryString string) (map[string]string, error) {
-	timestamp := strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10)
+	return c.prepareAuthHeaders(method, uri, queryString, 0)
+}
+
+// prepareAuthHeaders signs with the local clock shifted by offset, the
+// measured difference between the API gateway's clock and ours.
+func (c *Client) prepareAuthHeaders(method, uri, queryString string, offset time.Duration) (map[string]string, error) {
+	if err := c.refreshCredentials(); err != nil {
+		return nil, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Add(offset).UnixNano()/int64(time.Millisecond), 10)
 	signature := GenerateHMACSignature(method, uri, queryString, timestamp, c.accessKey, c.secretKey)
 
 	headers := map[string]string{
@@ -40,6 +54,9 @@ func (c *Client) PrepareAuthHeaders(method, uri, queryString string) (map[string
 		"x-ncp-apigw-signature-v2": signature,
 		"Content-Type":             "application/json",
 	}
+	if c.sessionToken != "" {
+		headers[sessionTokenHeader] = c.sessionToken
+	}
 
 	return headers, nil
 }
@@ -1,22 +1,48 @@
 package ncp
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 // Authenticator wraps the ncp-iam-authenticator binary.
 type Authenticator struct {
+	// Timeout bounds each invocation (default DefaultAuthenticatorTimeout).
+	Timeout time.Duration
+
 	binaryPath string
 	profile    string
+	creds      *Config
 }
 
 // NewAuthenticator creates an Authenticator, locating the ncp-iam-authenticator binary.
 func NewAuthenticator(profile string) *Authenticator {
+	return NewAuthenticatorForPath(profile, findBinary())
+}
+
+// NewAuthenticatorForPath creates an Authenticator for the binary at path.
+// Bare names are looked up in $PATH; the result is made absolute so it can be
+// recorded in kubeconfig users independently of the caller's PATH.
+func NewAuthenticatorForPath(profile, path string) *Authenticator {
+	if !strings.ContainsRune(path, filepath.Separator) {
+		p, err := exec.LookPath(path)
+		if err != nil {
+			return &Authenticator{binaryPath: path, profile: profile}
+		}
+		path = p
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
 	return &Authenticator{
-		binaryPath: findBinary(),
+		binaryPath: path,
 		profile:    profile,
 	}
 }
@@ -40,6 +66,44 @@ func findBinary() string {
 	return "ncp-iam-authenticator"
 }
 
+// SetCredentials makes the authenticator pass cfg's keys to ncp-iam-authenticator
+// through NCLOUD_* environment variables, so credentials resolved from sources it
+// cannot read itself (vault, credential_process) are still used.
+func (a *Authenticator) SetCredentials(cfg *Config) {
+	a.creds = cfg
+}
+
+// Path returns the ncp-iam-authenticator binary the Authenticator runs.
+func (a *Authenticator) Path() string {
+	return a.binaryPath
+}
+
+// Version returns the output of `ncp-iam-authenticator version`.
+func (a *Authenticator) Version() (string, error) {
+	var out bytes.Buffer
+	if err := a.run(context.Background(), []string{"version"}, &out, nil); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// CheckVersion parses the binary's version and fails if it is older than
+// MinAuthenticatorVersion.
+func (a *Authenticator) CheckVersion() (Version, error) {
+	out, err := a.Version()
+	if err != nil {
+		return Version{}, err
+	}
+	v, err := ParseVersion(out)
+	if err != nil {
+		return Version{}, fmt.Errorf("cannot determine ncp-iam-authenticator version: %w", err)
+	}
+	if v.Less(MinAuthenticatorVersion) {
+		return v, fmt.Errorf("ncp-iam-authenticator %s at %s is older than the minimum supported %s", v, a.binaryPath, MinAuthenticatorVersion)
+	}
+	return v, nil
+}
+
 // IsInstalled checks if ncp-iam-authenticator is available.
 func (a *Authenticator) IsInstalled() bool {
 	_, err := exec.LookPath(a.binaryPath)
@@ -50,18 +114,30 @@ func (a *Authenticator) IsInstalled() bool {
 }
 
 // UpdateKubeconfig runs ncp-iam-authenticator to add/update a cluster entry in kubeconfig.
-func (a *Authenticator) UpdateKubeconfig(cluster Cluster, kubeconfigPath string, overwrite bool) error {
+//
+// The authenticator edits a temporary copy which replaces kubeconfig only on
+// success, so a failed, timed out or cancelled run leaves the file untouched.
+func (a *Authenticator) UpdateKubeconfig(ctx context.Context, cluster Cluster, kubeconfigPath string, overwrite bool) error {
+	if resolved, err := filepath.EvalSymlinks(kubeconfigPath); err == nil {
+		kubeconfigPath = resolved
+	}
 	dir := filepath.Dir(kubeconfigPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("failed to create kubeconfig directory: %w", err)
 	}
 
+	tmp, err := copyToTemp(kubeconfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to stage kubeconfig: %w", err)
+	}
+	defer os.Remove(tmp)
+
 	args := []string{
 		"update-kubeconfig",
 		"--region", cluster.Region,
 		"--clusterUuid", cluster.UUID,
 		"--clusterName", cluster.Name,
-		"--kubeconfig", kubeconfigPath,
+		"--kubeconfig", tmp,
 	}
 
 	if a.profile != "" {
@@ -72,13 +148,112 @@ func (a *Authenticator) UpdateKubeconfig(cluster Cluster, kubeconfigPath string,
 		args = append(args, "--overwrite")
 	}
 
-	cmd := exec.Command(a.binaryPath, args...)
-	cmd.Env = os.Environ()
+	if err := a.run(ctx, args, io.Discard, nil); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, kubeconfigPath)
+}
+
+// Token runs `ncp-iam-authenticator token` for a cluster and writes the
+// ExecCredential JSON to stdout. The authenticator's stderr is passed through
+// for kubectl to display.
+func (a *Authenticator) Token(ctx context.Context, clusterUUID, region string, stdout io.Writer) error {
+	args := []string{
+		"token",
+		"--clusterUuid", clusterUUID,
+		"--region", region,
+	}
+
+	if a.profile != "" && a.creds == nil {
+		args = append(args, "--profile", a.profile)
+	}
+
+	return a.run(ctx, args, stdout, os.Stderr)
+}
+
+// copyToTemp copies path (if it exists) to a new file in the same directory
+// and returns the copy's name. The copy keeps the original's permissions.
+func copyToTemp(path string) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".nks-ctx-*")
+	if err != nil {
+		return "", err
+	}
+	defer tmp.Close()
+
+	mode := os.FileMode(0600)
+	if src, err := os.Open(path); err == nil {
+		defer src.Close()
+		if info, err := src.Stat(); err == nil {
+			mode = info.Mode().Perm()
+		}
+		if _, err := io.Copy(tmp, src); err != nil {
+			os.Remove(tmp.Name())
+			return "", err
+		}
+	} else if !os.IsNotExist(err) {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	if err := tmp.Chmod(mode); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// credentialEnv are the variables environ controls when credentials are set.
+var credentialEnv = []string{
+	"NCLOUD_ACCESS_KEY", "NCLOUD_SECRET_KEY", "NCLOUD_SESSION_TOKEN",
+	"NCLOUD_API_GW", "NCLOUD_REGION", "NCLOUD_PROFILE",
+}
+
+// environ returns the subprocess environment. With credentials set, the
+// caller's NCLOUD_* variables are replaced by the resolved (pinned) values,
+// so a different account exported in the shell cannot leak in.
+func (a *Authenticator) environ() ([]string, error) {
+	env := os.Environ()
+	if a.creds == nil {
+		return env, nil
+	}
 
-	output, err := cmd.CombinedOutput()
+	fresh, err := a.creds.Fresh()
 	if err != nil {
-		return fmt.Errorf("ncp-iam-authenticator failed: %w\nOutput: %s", err, string(output))
+		return nil, err
 	}
+	a.creds = fresh
 
-	return nil
+	env = WithoutEnv(env, credentialEnv...)
+	env = append(env,
+		"NCLOUD_ACCESS_KEY="+a.creds.AccessKey,
+		"NCLOUD_SECRET_KEY="+a.creds.SecretKey,
+		"NCLOUD_API_GW="+a.creds.ApiURL,
+	)
+	if a.creds.SessionToken != "" {
+		env = append(env, "NCLOUD_SESSION_TOKEN="+a.creds.SessionToken)
+	}
+	if a.creds.Region != "" {
+		env = append(env, "NCLOUD_REGION="+a.creds.Region)
+	}
+	return env, nil
+}
+
+// WithoutEnv returns a copy of env, in os.Environ form, without the named
+// variables.
+func WithoutEnv(env []string, names ...string) []string {
+	out := env[:0:0]
+	for _, kv := range env {
+		drop := false
+		for _, name := range names {
+			if strings.HasPrefix(kv, name+"=") {
+				drop = true
+				break
+			}
+		}
+		if !drop {
+			out = append(out, kv)
+		}
+	}
+	return out
 }
@@ -0,0 +1,137 @@
+package ncp
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeAuthenticator writes a shell script standing in for ncp-iam-authenticator.
+func fakeAuthenticator(t *testing.T, body string) *Authenticator {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ncp-iam-authenticator")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return NewAuthenticatorForPath("", path)
+}
+
+func TestAuthenticator_ErrorCarriesExitCodeAndStderr(t *testing.T) {
+	a := fakeAuthenticator(t, `echo "progress" ; echo "warming up" >&2 ; echo "invalid region: XX" >&2 ; exit 3`)
+
+	err := a.Token(context.Background(), "uuid", "XX", os.Stdout)
+	var aerr *AuthenticatorError
+	if !errors.As(err, &aerr) {
+		t.Fatalf("Token() error = %v, want *AuthenticatorError", err)
+	}
+	if aerr.ExitCode != 3 || aerr.Command != "token" {
+		t.Errorf("ExitCode = %d, Command = %q; want 3, token", aerr.ExitCode, aerr.Command)
+	}
+	if aerr.Stderr != "warming up\ninvalid region: XX" {
+		t.Errorf("Stderr = %q", aerr.Stderr)
+	}
+	if aerr.Reason() != "invalid region: XX" {
+		t.Errorf("Reason() = %q, want last stderr line", aerr.Reason())
+	}
+}
+
+func TestAuthenticator_TimeoutKillsProcessGroup(t *testing.T) {
+	// The child sleep keeps stdout open; only a group kill lets Run return promptly.
+	a := fakeAuthenticator(t, `sleep 30 & wait`)
+	a.Timeout = 200 * time.Millisecond
+
+	start := time.Now()
+	err := a.Token(context.Background(), "uuid", "KR", os.Stdout)
+	var aerr *AuthenticatorError
+	if !errors.As(err, &aerr) || !aerr.TimedOut {
+		t.Fatalf("Token() error = %v, want timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Token() took %v after timeout", elapsed)
+	}
+}
+
+func TestAuthenticator_Cancel(t *testing.T) {
+	a := fakeAuthenticator(t, `sleep 30`)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	err := a.Token(ctx, "uuid", "KR", os.Stdout)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Token() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestAuthenticator_UpdateKubeconfigAtomic(t *testing.T) {
+	dir := t.TempDir()
+	kubeconfigPath := filepath.Join(dir, "config")
+	if err := os.WriteFile(kubeconfigPath, []byte("original\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Appends to the file named by --kubeconfig, then optionally fails.
+	script := `while [ $# -gt 0 ]; do [ "$1" = "--kubeconfig" ] && f="$2"; shift; done
+echo "added" >> "$f"
+exit ${FAIL:-0}`
+	a := fakeAuthenticator(t, script)
+	cluster := Cluster{UUID: "uuid", Name: "c1", Region: "KR"}
+
+	t.Setenv("FAIL", "1")
+	if err := a.UpdateKubeconfig(context.Background(), cluster, kubeconfigPath, false); err == nil {
+		t.Fatal("UpdateKubeconfig() expected error")
+	}
+	if got, _ := os.ReadFile(kubeconfigPath); string(got) != "original\n" {
+		t.Errorf("kubeconfig after failure = %q, want untouched", got)
+	}
+
+	t.Setenv("FAIL", "0")
+	if err := a.UpdateKubeconfig(context.Background(), cluster, kubeconfigPath, false); err != nil {
+		t.Fatalf("UpdateKubeconfig() error = %v", err)
+	}
+	if got, _ := os.ReadFile(kubeconfigPath); string(got) != "original\nadded\n" {
+		t.Errorf("kubeconfig after success = %q", got)
+	}
+	info, err := os.Stat(kubeconfigPath)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("kubeconfig mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestAuthenticator_CredentialsOverrideCallerEnv(t *testing.T) {
+	t.Setenv("NCLOUD_ACCESS_KEY", "caller-key")
+	t.Setenv("NCLOUD_PROFILE", "other")
+	t.Setenv("NCLOUD_API_GW", "https://caller.example.com")
+
+	out := filepath.Join(t.TempDir(), "env")
+	a := fakeAuthenticator(t, `env | grep ^NCLOUD_ | sort > "`+out+`"`)
+	a.SetCredentials(&Config{AccessKey: "pinned-key", SecretKey: "sk", ApiURL: "https://fin.example.com", Region: "FKR"})
+
+	if err := a.Token(context.Background(), "uuid", "FKR", os.Stdout); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(out)
+	want := "NCLOUD_ACCESS_KEY=pinned-key\nNCLOUD_API_GW=https://fin.example.com\nNCLOUD_REGION=FKR\nNCLOUD_SECRET_KEY=sk\n"
+	if string(got) != want {
+		t.Errorf("authenticator env =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWithoutEnv(t *testing.T) {
+	env := []string{"KUBECONFIG=/a", "KUBECONFIG_EXTRA=1", "HOME=/root", "NKS_CTX_CLUSTER=prod"}
+	got := WithoutEnv(env, "KUBECONFIG", "NKS_CTX_CLUSTER")
+	if strings.Join(got, " ") != "KUBECONFIG_EXTRA=1 HOME=/root" {
+		t.Errorf("WithoutEnv() = %v", got)
+	}
+	if env[0] != "KUBECONFIG=/a" {
+		t.Errorf("WithoutEnv() modified its input: %v", env)
+	}
+}
@@ -7,15 +7,22 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 )
 
 // Client communicates with the NCP NKS API.
 type Client struct {
-	accessKey   string
-	secretKey   string
-	apiGw       string   // ncloud API URL (for auth/signing)
-	nksBaseURLs []string // NKS API base URLs per region
+	accessKey    string
+	secretKey    string
+	sessionToken string   // set for temporary credentials
+	apiGw        string   // ncloud API URL (for auth/signing)
+	nksBaseURLs  []string // NKS API base URLs per region
+
+	mu    sync.Mutex
+	creds *Config // source of the keys above, refreshed when it expires
+
+	skew *SkewStore // clock offsets per API gateway host; nil disables compensation
 }
 
 // Cluster represents an NKS cluster.
@@ -63,23 +70,77 @@ func resolveNKSBaseURLs(apiURL string) []string {
 	}
 }
 
-// NewClientFromConfig creates an NCP client from a Config.
+// NewClientFromConfig creates an NCP client from a Config, compensating clock
+// skew with the offsets cached at DefaultSkewStorePath.
 func NewClientFromConfig(cfg *Config) *Client {
+	return newClient(cfg, LoadSkewStore(DefaultSkewStorePath()))
+}
+
+// newClient creates an NCP client that keeps clock offsets in skew.
+func newClient(cfg *Config, skew *SkewStore) *Client {
 	return &Client{
-		accessKey:   cfg.AccessKey,
-		secretKey:   cfg.SecretKey,
-		apiGw:       cfg.ApiURL,
-		nksBaseURLs: resolveNKSBaseURLs(cfg.ApiURL),
+		accessKey:    cfg.AccessKey,
+		secretKey:    cfg.SecretKey,
+		sessionToken: cfg.SessionToken,
+		apiGw:        cfg.ApiURL,
+		nksBaseURLs:  resolveNKSBaseURLs(cfg.ApiURL),
+		creds:        cfg,
+		skew:         skew,
+	}
+}
+
+// refreshCredentials renews temporary credentials that are about to expire
+// through the Config's provider callback.
+func (c *Client) refreshCredentials() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.creds == nil {
+		return nil
+	}
+	fresh, err := c.creds.Fresh()
+	if err != nil {
+		return err
+	}
+	if fresh != c.creds {
+		c.creds = fresh
+		c.accessKey = fresh.AccessKey
+		c.secretKey = fresh.SecretKey
+		c.sessionToken = fresh.SessionToken
 	}
+	return nil
 }
 
 // ListClusters retrieves clusters from all regional NKS API endpoints.
 // If some endpoints fail but others succeed, warnings are printed and partial results are returned.
 // If all endpoints fail, an error is returned.
 func (c *Client) ListClusters() ([]Cluster, error) {
+	allClusters, errors := c.listClusters()
+
+	if len(errors) > 0 && len(errors) == len(c.nksBaseURLs) {
+		return nil, fmt.Errorf("all API endpoints failed:\n  %s", strings.Join(errors, "\n  "))
+	}
+
+	for _, e := range errors {
+		fmt.Fprintf(os.Stderr, "  Warning: %s\n", e)
+	}
+
+	return allClusters, nil
+}
+
+// ListClustersStrict is like ListClusters but fails if any endpoint fails,
+// for callers that must not mistake a missing region for deleted clusters.
+func (c *Client) ListClustersStrict() ([]Cluster, error) {
+	allClusters, errors := c.listClusters()
+	if len(errors) > 0 {
+		return nil, fmt.Errorf("API endpoints failed:\n  %s", strings.Join(errors, "\n  "))
+	}
+	return allClusters, nil
+}
+
+func (c *Client) listClusters() ([]Cluster, []string) {
 	var allClusters []Cluster
 	var errors []string
-	successCount := 0
 
 	for _, baseURL := range c.nksBaseURLs {
 		clusters, err := c.listClustersFromEndpoint(baseURL)
@@ -87,34 +148,116 @@ func (c *Client) ListClusters() ([]Cluster, error) {
 			errors = append(errors, fmt.Sprintf("%s: %v", baseURL, err))
 			continue
 		}
-		successCount++
 		allClusters = append(allClusters, clusters...)
 	}
+	return allClusters, errors
+}
 
-	if successCount == 0 && len(errors) > 0 {
-		return nil, fmt.Errorf("all API endpoints failed:\n  %s", strings.Join(errors, "\n  "))
+// Validate makes a signed request to the first regional endpoint to confirm
+// that the API gateway accepts the credentials.
+func (c *Client) Validate() error {
+	if len(c.nksBaseURLs) == 0 {
+		return fmt.Errorf("no NKS endpoints for %s", c.apiGw)
 	}
+	return c.ValidateEndpoint(c.nksBaseURLs[0])
+}
 
-	for _, e := range errors {
-		fmt.Fprintf(os.Stderr, "  Warning: %s\n", e)
-	}
+// Endpoints returns the regional NKS API base URLs the client queries.
+func (c *Client) Endpoints() []string {
+	return c.nksBaseURLs
+}
 
-	return allClusters, nil
+// ValidateEndpoint makes a signed request to one regional endpoint.
+// A response that is not 200 OK is returned as an *APIError.
+func (c *Client) ValidateEndpoint(baseURL string) error {
+	_, err := c.listClustersFromEndpoint(baseURL)
+	return err
+}
+
+// APIError is a response from the NKS API other than 200 OK.
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API request failed (status %d): %s", e.StatusCode, e.Body)
+}
+
+// Unauthorized reports whether the gateway rejected the credentials or signature.
+func (e *APIError) Unauthorized() bool {
+	return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
 }
 
 func (c *Client) listClustersFromEndpoint(baseURL string) ([]Cluster, error) {
-	url := fmt.Sprintf("%s/clusters", baseURL)
-	uri := ExtractURI(url)
-	method := "GET"
+	body, err := c.get(fmt.Sprintf("%s/clusters", baseURL))
+	if err != nil {
+		return nil, err
+	}
+
+	var listResp clusterListResponse
+	if err := json.Unmarshal(body, &listResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w (body: %s)", err, string(body))
+	}
 
-	headers, err := c.PrepareAuthHeaders(method, uri, "")
+	clusters := make([]Cluster, 0, len(listResp.Clusters))
+	for _, info := range listResp.Clusters {
+		clusters = append(clusters, Cluster{
+			UUID:   info.UUID,
+			Name:   info.Name,
+			Region: info.RegionCode,
+			Status: info.Status,
+		})
+	}
+
+	return clusters, nil
+}
+
+// get performs a signed GET request and returns the response body.
+//
+// Requests are signed with the offset stored for the gateway host. If the
+// gateway rejects the timestamp, the offset is re-measured from the response
+// Date header, stored for later invocations and the request retried once.
+func (c *Client) get(url string) ([]byte, error) {
+	host := hostOf(url)
+	offset := c.skew.Offset(host)
+
+	resp, body, sent, received, err := c.send("GET", url, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	if isTimestampRejection(resp, body) {
+		if measured, ok := serverOffset(resp, sent, received); ok && absDuration(measured-offset) >= time.Second {
+			if err := c.skew.Set(host, measured); err != nil {
+				fmt.Fprintf(os.Stderr, "  Warning: failed to store clock offset: %v\n", err)
+			}
+			resp, body, _, _, err = c.send("GET", url, measured)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return body, nil
+}
+
+// send signs and performs one request, returning the full body and the
+// local times the request was sent and answered.
+func (c *Client) send(method, url string, offset time.Duration) (*http.Response, []byte, time.Time, time.Time, error) {
+	var sent, received time.Time
+
+	headers, err := c.prepareAuthHeaders(method, ExtractURI(url), ExtractQueryString(url), offset)
 	if err != nil {
-		return nil, fmt.Errorf("failed to prepare auth headers: %w", err)
+		return nil, nil, sent, received, fmt.Errorf("failed to prepare auth headers: %w", err)
 	}
 
 	req, err := http.NewRequest(method, url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, nil, sent, received, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	for key, value := range headers {
@@ -122,36 +265,68 @@ func (c *Client) listClustersFromEndpoint(baseURL string) ([]Cluster, error) {
 	}
 
 	httpClient := &http.Client{Timeout: 30 * time.Second}
+	sent = time.Now()
 	resp, err := httpClient.Do(req)
+	received = time.Now()
 	if err != nil {
-		return nil, fmt.Errorf("API request failed: %w", err)
+		return nil, nil, sent, received, fmt.Errorf("API request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API request failed (status %d): %s", resp.StatusCode, string(body))
-	}
-
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, nil, sent, received, fmt.Errorf("failed to read response: %w", err)
 	}
+	return resp, body, sent, received, nil
+}
 
-	var listResp clusterListResponse
-	if err := json.Unmarshal(body, &listResp); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w (body: %s)", err, string(body))
-	}
+// SkewReport is the clock offset measured against one API gateway host.
+type SkewReport struct {
+	Host   string
+	Offset time.Duration // server time minus local time
+	Stored time.Duration // offset currently applied when signing
+}
 
-	clusters := make([]Cluster, 0, len(listResp.Clusters))
-	for _, info := range listResp.Clusters {
-		clusters = append(clusters, Cluster{
-			UUID:   info.UUID,
-			Name:   info.Name,
-			Region: info.RegionCode,
-			Status: info.Status,
-		})
+// Skew returns how far the local clock is off, in either direction.
+func (r SkewReport) Skew() time.Duration {
+	return absDuration(r.Offset)
+}
+
+// Residual returns how far signed timestamps are still off after applying
+// the stored offset, in either direction.
+func (r SkewReport) Residual() time.Duration {
+	return absDuration(r.Offset - r.Stored)
+}
+
+// MeasureSkew compares the local clock with the Date header of each NKS
+// endpoint host. The request does not need to be accepted to be measured.
+func (c *Client) MeasureSkew() ([]SkewReport, error) {
+	seen := make(map[string]bool)
+	var reports []SkewReport
+
+	for _, baseURL := range c.nksBaseURLs {
+		host := hostOf(baseURL)
+		if seen[host] {
+			continue
+		}
+		seen[host] = true
+
+		resp, _, sent, received, err := c.send("GET", fmt.Sprintf("%s/clusters", baseURL), c.skew.Offset(host))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", host, err)
+		}
+		offset, ok := serverOffset(resp, sent, received)
+		if !ok {
+			return nil, fmt.Errorf("%s: response has no Date header", host)
+		}
+		reports = append(reports, SkewReport{Host: host, Offset: offset, Stored: c.skew.Offset(host)})
 	}
+	return reports, nil
+}
 
-	return clusters, nil
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
 }
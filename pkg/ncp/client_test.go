@@ -5,7 +5,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestLoadConfig_FromEnv(t *testing.T) {
@@ -74,7 +76,14 @@ func TestNewClientFromConfig(t *testing.T) {
 		SecretKey: "sk",
 		ApiURL:    "https://ncloud.apigw.ntruss.com",
 	}
+	// Keep the clock offsets out of the user's cache directory.
+	cache := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cache)
+
 	client := NewClientFromConfig(cfg)
+	if want := filepath.Join(cache, "nks-ctx", "clock-skew.json"); client.skew == nil || client.skew.path != want {
+		t.Errorf("skew store = %+v, want one at %s", client.skew, want)
+	}
 	if client.accessKey != "ak" {
 		t.Errorf("accessKey = %v, want ak", client.accessKey)
 	}
@@ -187,3 +196,66 @@ func restoreEnv(key, value string) {
 		os.Unsetenv(key)
 	}
 }
+
+func TestClient_SessionTokenRefresh(t *testing.T) {
+	var gotKeys, gotTokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKeys = append(gotKeys, r.Header.Get("x-ncp-iam-access-key"))
+		gotTokens = append(gotTokens, r.Header.Get(sessionTokenHeader))
+		json.NewEncoder(w).Encode(clusterListResponse{})
+	}))
+	defer server.Close()
+
+	refreshes := 0
+	cfg := &Config{
+		AccessKey:    "old-ak",
+		SecretKey:    "old-sk",
+		SessionToken: "old-token",
+		ApiURL:       server.URL,
+		Expiration:   time.Now().Add(10 * time.Second),
+		Refresh: func() (*Config, error) {
+			refreshes++
+			return &Config{
+				AccessKey:    "new-ak",
+				SecretKey:    "new-sk",
+				SessionToken: "new-token",
+				ApiURL:       server.URL,
+				Expiration:   time.Now().Add(time.Hour),
+			}, nil
+		},
+	}
+
+	client := newClient(cfg, tempSkewStore(t))
+	client.nksBaseURLs = []string{server.URL, server.URL}
+
+	if _, err := client.ListClusters(); err != nil {
+		t.Fatalf("ListClusters() error = %v", err)
+	}
+
+	if refreshes != 1 {
+		t.Errorf("refreshes = %d, want 1", refreshes)
+	}
+	for i := range gotKeys {
+		if gotKeys[i] != "new-ak" || gotTokens[i] != "new-token" {
+			t.Errorf("request %d signed with %s/%s, want refreshed credentials", i, gotKeys[i], gotTokens[i])
+		}
+	}
+}
+
+func TestClient_NoSessionTokenHeader(t *testing.T) {
+	client := newClient(&Config{AccessKey: "ak", SecretKey: "sk", ApiURL: "https://ncloud.apigw.ntruss.com"}, tempSkewStore(t))
+
+	headers, err := client.PrepareAuthHeaders("GET", "/vnks/v2/clusters", "")
+	if err != nil {
+		t.Fatalf("PrepareAuthHeaders() error = %v", err)
+	}
+	if _, ok := headers[sessionTokenHeader]; ok {
+		t.Error("long-lived credentials should not send a session token header")
+	}
+}
+
+// tempSkewStore returns an empty clock offset store that does not touch the
+// user's cache.
+func tempSkewStore(t *testing.T) *SkewStore {
+	return LoadSkewStore(filepath.Join(t.TempDir(), "clock-skew.json"))
+}
@@ -1,11 +1,11 @@
 package ncp
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
+	"sync"
+	"time"
 )
 
 // Config holds NCP credentials and API configuration.
@@ -14,39 +14,65 @@ type Config struct {
 	SecretKey string
 	ApiURL    string
 	Region    string
+
+	// SessionToken accompanies temporary credentials; empty for long-lived keys.
+	SessionToken string
+	// Expiration is when temporary credentials stop working; zero if they do not expire.
+	Expiration time.Time
+	// Refresh re-resolves the credentials from the provider that supplied them.
+	// It is set by ChainProvider and used when temporary credentials expire.
+	Refresh func() (*Config, error)
+
+	// Profile is the profile name the credentials were resolved for.
+	Profile string
+	// Source is the name of the CredentialsProvider that supplied the keys.
+	Source string
+	// Warnings lists malformed lines of the configure file outside the
+	// profile used, prefixed with file:line. They did not prevent loading
+	// this profile; commands the user runs directly should show them.
+	Warnings []string
 }
 
-// LoadConfig loads NCP configuration from environment variables or ~/.ncloud/configure.
+// LoadConfig loads NCP configuration through the DefaultChain of credential providers.
 // Environment variables take precedence over the config file.
 // If profile is empty, "DEFAULT" is used.
 func LoadConfig(profile string) (*Config, error) {
-	cfg := &Config{
-		AccessKey: os.Getenv("NCLOUD_ACCESS_KEY"),
-		SecretKey: os.Getenv("NCLOUD_SECRET_KEY"),
-		ApiURL:    os.Getenv("NCLOUD_API_GW"),
-		Region:    os.Getenv("NCLOUD_REGION"),
-	}
-
-	if cfg.AccessKey != "" && cfg.SecretKey != "" {
-		if cfg.ApiURL == "" {
-			cfg.ApiURL = defaultAPIURL()
-		}
-		return cfg, nil
-	}
-
-	fileCfg, err := loadFromFile(configFilePath(), profile)
+	cfg, err := DefaultChain().Retrieve(profile)
 	if err != nil {
 		return nil, fmt.Errorf(
-			"NCP credentials not found.\n\n" +
-				"Set environment variables:\n" +
-				"  export NCLOUD_ACCESS_KEY=\"your-access-key\"\n" +
-				"  export NCLOUD_SECRET_KEY=\"your-secret-key\"\n" +
-				"  export NCLOUD_API_GW=\"https://ncloud.apigw.ntruss.com\"  # optional\n\n" +
-				"Or configure ~/.ncloud/configure with a profile.",
+			"NCP credentials not found.\n\n"+
+				"Set environment variables:\n"+
+				"  export NCLOUD_ACCESS_KEY=\"your-access-key\"\n"+
+				"  export NCLOUD_SECRET_KEY=\"your-secret-key\"\n"+
+				"  export NCLOUD_API_GW=\"https://ncloud.apigw.ntruss.com\"  # optional\n\n"+
+				"Or configure ~/.ncloud/configure with a profile.\n\n%v",
+			err,
 		)
 	}
 
-	return fileCfg, nil
+	return cfg, nil
+}
+
+// refreshWindow is how long before expiry temporary credentials are renewed.
+const refreshWindow = time.Minute
+
+// ExpiresWithin reports whether the credentials expire within d.
+// Credentials without an Expiration never expire.
+func (c *Config) ExpiresWithin(d time.Duration) bool {
+	return !c.Expiration.IsZero() && time.Now().Add(d).After(c.Expiration)
+}
+
+// Fresh returns c, or newly resolved credentials if c is about to expire and
+// can be refreshed.
+func (c *Config) Fresh() (*Config, error) {
+	if !c.ExpiresWithin(refreshWindow) || c.Refresh == nil {
+		return c, nil
+	}
+	fresh, err := c.Refresh()
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh expired credentials: %w", err)
+	}
+	return fresh, nil
 }
 
 func defaultAPIURL() string {
@@ -58,7 +84,8 @@ func configFilePath() string {
 	return filepath.Join(home, ".ncloud", "configure")
 }
 
-// loadFromFile parses the INI-style ~/.ncloud/configure file.
+// loadFromFile parses the INI-style ~/.ncloud/configure file
+// (see ConfigFile for the accepted syntax).
 //
 // Expected format:
 //
@@ -67,54 +94,26 @@ func configFilePath() string {
 //	ncloud_secret_access_key=YOUR_SECRET_KEY
 //	ncloud_api_url=https://ncloud.apigw.ntruss.com
 //	ncloud_region=KR
+//	ncloud_session_token=OPTIONAL_TEMPORARY_TOKEN
 func loadFromFile(path, profile string) (*Config, error) {
-	if profile == "" {
-		profile = "DEFAULT"
-	}
+	return loadProfile(newConfigSource(path), profile)
+}
+
+func loadProfile(src *configSource, profile string) (*Config, error) {
+	profile = profileOrDefault(profile)
 
-	file, err := os.Open(path)
+	data, err := src.profile(profile)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
-
-	sections := make(map[string]map[string]string)
-	currentSection := "DEFAULT"
-	sections[currentSection] = make(map[string]string)
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-
-		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
-			continue
-		}
-
-		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
-			currentSection = strings.TrimSpace(line[1 : len(line)-1])
-			if sections[currentSection] == nil {
-				sections[currentSection] = make(map[string]string)
-			}
-			continue
-		}
-
-		if idx := strings.Index(line, "="); idx > 0 {
-			key := strings.TrimSpace(line[:idx])
-			value := strings.TrimSpace(line[idx+1:])
-			sections[currentSection][key] = value
-		}
-	}
-
-	data, ok := sections[profile]
-	if !ok {
-		return nil, fmt.Errorf("profile '%s' not found in %s", profile, path)
-	}
 
 	cfg := &Config{
-		AccessKey: data["ncloud_access_key_id"],
-		SecretKey: data["ncloud_secret_access_key"],
-		ApiURL:    data["ncloud_api_url"],
-		Region:    data["ncloud_region"],
+		AccessKey:    data["ncloud_access_key_id"],
+		SecretKey:    data["ncloud_secret_access_key"],
+		ApiURL:       data["ncloud_api_url"],
+		Region:       data["ncloud_region"],
+		SessionToken: data["ncloud_session_token"],
+		Profile:      profile,
 	}
 
 	if cfg.AccessKey == "" || cfg.SecretKey == "" {
@@ -127,3 +126,52 @@ func loadFromFile(path, profile string) (*Config, error) {
 
 	return cfg, nil
 }
+
+// configSource parses a configure file on first use, so the providers of a
+// chain share one parse.
+type configSource struct {
+	path string
+
+	once sync.Once
+	file *ConfigFile
+	err  error
+}
+
+func newConfigSource(path string) *configSource {
+	return &configSource{path: path}
+}
+
+func (s *configSource) load() (*ConfigFile, error) {
+	s.once.Do(func() {
+		s.file, s.err = readConfigFile(s.path)
+	})
+	return s.file, s.err
+}
+
+// profile returns the key/value pairs of a profile, including keys inherited
+// through source_profile. Malformed lines in other profiles are ignored, so a
+// typo in one profile does not break the rest; see warnings.
+func (s *configSource) profile(name string) (map[string]string, error) {
+	file, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return file.Resolve(profileOrDefault(name))
+}
+
+// warnings returns the malformed lines of the file, prefixed with file:line.
+func (s *configSource) warnings() []string {
+	file, err := s.load()
+	if err != nil {
+		return nil
+	}
+	serr, ok := file.syntaxError(func(string) bool { return true }).(*SyntaxError)
+	if !ok {
+		return nil
+	}
+	warnings := make([]string, 0, len(serr.Errors))
+	for _, le := range serr.Errors {
+		warnings = append(warnings, fmt.Sprintf("%s:%d: %s", s.path, le.Line, le.Msg))
+	}
+	return warnings
+}
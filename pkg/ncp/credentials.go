@@ -0,0 +1,273 @@
+package ncp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// CredentialsProvider supplies NCP credentials for a profile.
+type CredentialsProvider interface {
+	// Name identifies the provider in diagnostics (e.g. "env", "profile").
+	Name() string
+	// Retrieve returns a Config with at least AccessKey and SecretKey set.
+	Retrieve(profile string) (*Config, error)
+}
+
+// ChainProvider tries each provider in order and returns the first success.
+type ChainProvider struct {
+	Providers []CredentialsProvider
+
+	// source is the configure file the providers share, for Config.Warnings.
+	source *configSource
+}
+
+// DefaultChain returns the provider chain used by LoadConfig:
+// environment variables, the profile file, the encrypted vault, credential_process,
+// then the static file.
+func DefaultChain() *ChainProvider {
+	path := configFilePath()
+	source := newConfigSource(path)
+	vaultProvider := NewVaultProvider(path)
+	vaultProvider.source = source
+	return &ChainProvider{
+		Providers: []CredentialsProvider{
+			&EnvProvider{},
+			&ProfileFileProvider{Path: path, source: source},
+			vaultProvider,
+			&ProcessProvider{Path: path, source: source},
+			&StaticFileProvider{Path: os.Getenv("NCLOUD_CREDENTIALS_FILE")},
+		},
+		source: source,
+	}
+}
+
+// Name implements CredentialsProvider.
+func (c *ChainProvider) Name() string {
+	return "chain"
+}
+
+// Without returns a copy of the chain that skips the named providers.
+func (c *ChainProvider) Without(names ...string) *ChainProvider {
+	skip := make(map[string]bool)
+	for _, n := range names {
+		skip[n] = true
+	}
+	out := &ChainProvider{source: c.source}
+	for _, p := range c.Providers {
+		if !skip[p.Name()] {
+			out.Providers = append(out.Providers, p)
+		}
+	}
+	return out
+}
+
+// Retrieve implements CredentialsProvider.
+func (c *ChainProvider) Retrieve(profile string) (*Config, error) {
+	var errs []string
+	for _, p := range c.Providers {
+		cfg, err := p.Retrieve(profile)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", p.Name(), err))
+			continue
+		}
+		if cfg.ApiURL == "" {
+			cfg.ApiURL = defaultAPIURL()
+		}
+		if cfg.Profile == "" {
+			cfg.Profile = profileOrDefault(profile)
+		}
+		cfg.Source = p.Name()
+		if c.source != nil {
+			cfg.Warnings = c.source.warnings()
+		}
+		if !cfg.Expiration.IsZero() {
+			provider := p
+			cfg.Refresh = func() (*Config, error) {
+				return (&ChainProvider{Providers: []CredentialsProvider{provider}}).Retrieve(profile)
+			}
+		}
+		return cfg, nil
+	}
+	return nil, fmt.Errorf("no provider returned credentials:\n  %s", strings.Join(errs, "\n  "))
+}
+
+// EnvProvider reads NCLOUD_ACCESS_KEY, NCLOUD_SECRET_KEY, NCLOUD_SESSION_TOKEN,
+// NCLOUD_API_GW and NCLOUD_REGION.
+type EnvProvider struct{}
+
+// Name implements CredentialsProvider.
+func (p *EnvProvider) Name() string {
+	return "env"
+}
+
+// Retrieve implements CredentialsProvider.
+func (p *EnvProvider) Retrieve(profile string) (*Config, error) {
+	cfg := &Config{
+		AccessKey: os.Getenv("NCLOUD_ACCESS_KEY"),
+		SecretKey: os.Getenv("NCLOUD_SECRET_KEY"),
+		ApiURL:    os.Getenv("NCLOUD_API_GW"),
+		Region:    os.Getenv("NCLOUD_REGION"),
+
+		SessionToken: os.Getenv("NCLOUD_SESSION_TOKEN"),
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, fmt.Errorf("NCLOUD_ACCESS_KEY and NCLOUD_SECRET_KEY not set")
+	}
+	return cfg, nil
+}
+
+// ProfileFileProvider reads plaintext keys from a profile in ~/.ncloud/configure.
+type ProfileFileProvider struct {
+	Path string
+
+	source *configSource
+}
+
+// Name implements CredentialsProvider.
+func (p *ProfileFileProvider) Name() string {
+	return "profile"
+}
+
+// Retrieve implements CredentialsProvider.
+func (p *ProfileFileProvider) Retrieve(profile string) (*Config, error) {
+	return loadProfile(sourceFor(&p.source, p.Path), profile)
+}
+
+// sourceFor returns *source, parsing path into it if the provider was not
+// built by DefaultChain.
+func sourceFor(source **configSource, path string) *configSource {
+	if *source == nil {
+		*source = newConfigSource(path)
+	}
+	return *source
+}
+
+// ProcessProvider runs the profile's credential_process command and parses
+// its JSON output:
+//
+//	{"Version": 1, "AccessKeyId": "...", "SecretAccessKey": "...",
+//	 "SessionToken": "...", "Expiration": "2024-01-01T00:00:00Z"}
+//
+// SessionToken and Expiration are optional. Expiring credentials are
+// refreshed by running the command again. ncloud_api_url and ncloud_region are still taken from the profile.
+type ProcessProvider struct {
+	Path string
+
+	source *configSource
+}
+
+type processCredentials struct {
+	Version         int       `json:"Version"`
+	AccessKeyID     string    `json:"AccessKeyId"`
+	SecretAccessKey string    `json:"SecretAccessKey"`
+	SessionToken    string    `json:"SessionToken,omitempty"`
+	Expiration      time.Time `json:"Expiration,omitempty"`
+}
+
+// Name implements CredentialsProvider.
+func (p *ProcessProvider) Name() string {
+	return "credential_process"
+}
+
+// Retrieve implements CredentialsProvider.
+func (p *ProcessProvider) Retrieve(profile string) (*Config, error) {
+	data, err := sourceFor(&p.source, p.Path).profile(profile)
+	if err != nil {
+		return nil, err
+	}
+	command := data["credential_process"]
+	if command == "" {
+		return nil, fmt.Errorf("credential_process not set in profile '%s'", profileOrDefault(profile))
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("credential_process failed: %w", err)
+	}
+
+	var creds processCredentials
+	if err := json.Unmarshal(stdout.Bytes(), &creds); err != nil {
+		return nil, fmt.Errorf("credential_process returned invalid JSON: %w", err)
+	}
+	if creds.Version != 1 {
+		return nil, fmt.Errorf("credential_process returned unsupported Version %d", creds.Version)
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, fmt.Errorf("credential_process returned incomplete credentials")
+	}
+
+	return &Config{
+		AccessKey:    creds.AccessKeyID,
+		SecretKey:    creds.SecretAccessKey,
+		ApiURL:       data["ncloud_api_url"],
+		Region:       data["ncloud_region"],
+		SessionToken: creds.SessionToken,
+		Expiration:   creds.Expiration,
+	}, nil
+}
+
+// StaticFileProvider reads a JSON file in the credential_process format.
+// The file is ignored unless Path is set (NCLOUD_CREDENTIALS_FILE).
+type StaticFileProvider struct {
+	Path string
+}
+
+type staticCredentials struct {
+	processCredentials
+	ApiURL string `json:"ApiUrl"`
+	Region string `json:"Region"`
+}
+
+// Name implements CredentialsProvider.
+func (p *StaticFileProvider) Name() string {
+	return "static_file"
+}
+
+// Retrieve implements CredentialsProvider.
+func (p *StaticFileProvider) Retrieve(profile string) (*Config, error) {
+	if p.Path == "" {
+		return nil, fmt.Errorf("NCLOUD_CREDENTIALS_FILE not set")
+	}
+	raw, err := os.ReadFile(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	var creds staticCredentials
+	if err := json.Unmarshal(raw, &creds); err != nil {
+		return nil, fmt.Errorf("invalid credentials file %s: %w", p.Path, err)
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, fmt.Errorf("incomplete credentials in %s", p.Path)
+	}
+	return &Config{
+		AccessKey:    creds.AccessKeyID,
+		SecretKey:    creds.SecretAccessKey,
+		ApiURL:       creds.ApiURL,
+		Region:       creds.Region,
+		SessionToken: creds.SessionToken,
+		Expiration:   creds.Expiration,
+	}, nil
+}
+
+// RedactSecret masks all but the last four characters of a secret.
+func RedactSecret(secret string) string {
+	if len(secret) <= 4 {
+		return strings.Repeat("*", len(secret))
+	}
+	return strings.Repeat("*", len(secret)-4) + secret[len(secret)-4:]
+}
+
+func profileOrDefault(profile string) string {
+	if profile == "" {
+		return "DEFAULT"
+	}
+	return profile
+}
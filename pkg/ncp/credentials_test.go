@@ -0,0 +1,154 @@
+package ncp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type stubProvider struct {
+	name string
+	cfg  *Config
+	err  error
+}
+
+func (s *stubProvider) Name() string { return s.name }
+
+func (s *stubProvider) Retrieve(profile string) (*Config, error) {
+	return s.cfg, s.err
+}
+
+func TestChainProvider_FirstSuccessWins(t *testing.T) {
+	chain := &ChainProvider{
+		Providers: []CredentialsProvider{
+			&stubProvider{name: "first", err: os.ErrNotExist},
+			&stubProvider{name: "second", cfg: &Config{AccessKey: "ak", SecretKey: "sk"}},
+			&stubProvider{name: "third", cfg: &Config{AccessKey: "other", SecretKey: "other"}},
+		},
+	}
+
+	cfg, err := chain.Retrieve("")
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if cfg.Source != "second" {
+		t.Errorf("Source = %v, want second", cfg.Source)
+	}
+	if cfg.Profile != "DEFAULT" {
+		t.Errorf("Profile = %v, want DEFAULT", cfg.Profile)
+	}
+	if cfg.ApiURL != defaultAPIURL() {
+		t.Errorf("ApiURL = %v, want default", cfg.ApiURL)
+	}
+}
+
+func TestChainProvider_AllFail(t *testing.T) {
+	chain := &ChainProvider{
+		Providers: []CredentialsProvider{
+			&stubProvider{name: "a", err: os.ErrNotExist},
+			&stubProvider{name: "b", err: os.ErrPermission},
+		},
+	}
+
+	if _, err := chain.Retrieve(""); err == nil {
+		t.Error("Retrieve() expected error when all providers fail")
+	}
+}
+
+func TestProcessProvider(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "configure")
+
+	content := `[DEFAULT]
+credential_process=echo '{"Version": 1, "AccessKeyId": "proc-ak", "SecretAccessKey": "proc-sk"}'
+ncloud_region=KR
+
+[temporary]
+credential_process=echo '{"Version": 1, "AccessKeyId": "tmp-ak", "SecretAccessKey": "tmp-sk", "SessionToken": "tmp-token", "Expiration": "2030-01-02T03:04:05Z"}'
+
+[broken]
+credential_process=echo not-json
+
+[plain]
+ncloud_access_key_id=key
+ncloud_secret_access_key=secret
+`
+	if err := os.WriteFile(configPath, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &ProcessProvider{Path: configPath}
+
+	cfg, err := p.Retrieve("")
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if cfg.AccessKey != "proc-ak" || cfg.SecretKey != "proc-sk" {
+		t.Errorf("keys = %v/%v, want proc-ak/proc-sk", cfg.AccessKey, cfg.SecretKey)
+	}
+	if cfg.Region != "KR" {
+		t.Errorf("Region = %v, want KR", cfg.Region)
+	}
+
+	tmp, err := (&ChainProvider{Providers: []CredentialsProvider{p}}).Retrieve("temporary")
+	if err != nil {
+		t.Fatalf("Retrieve(temporary) error = %v", err)
+	}
+	if tmp.SessionToken != "tmp-token" {
+		t.Errorf("SessionToken = %v, want tmp-token", tmp.SessionToken)
+	}
+	if tmp.Expiration.Year() != 2030 {
+		t.Errorf("Expiration = %v, want 2030-01-02", tmp.Expiration)
+	}
+	if tmp.Refresh == nil {
+		t.Error("Refresh not set for expiring credentials")
+	}
+
+	if _, err := p.Retrieve("broken"); err == nil {
+		t.Error("Retrieve(broken) expected error for invalid JSON")
+	}
+	if _, err := p.Retrieve("plain"); err == nil {
+		t.Error("Retrieve(plain) expected error without credential_process")
+	}
+}
+
+func TestStaticFileProvider(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "credentials.json")
+
+	content := `{"Version": 1, "AccessKeyId": "static-ak", "SecretAccessKey": "static-sk", "ApiUrl": "https://fin-ncloud.apigw.fin-ntruss.com"}`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := (&StaticFileProvider{Path: path}).Retrieve("")
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if cfg.AccessKey != "static-ak" {
+		t.Errorf("AccessKey = %v, want static-ak", cfg.AccessKey)
+	}
+	if cfg.ApiURL != "https://fin-ncloud.apigw.fin-ntruss.com" {
+		t.Errorf("ApiURL = %v, want fin URL", cfg.ApiURL)
+	}
+
+	if _, err := (&StaticFileProvider{}).Retrieve(""); err == nil {
+		t.Error("Retrieve() expected error when path is unset")
+	}
+}
+
+func TestRedactSecret(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"abc", "***"},
+		{"secret-key-1234", "***********1234"},
+	}
+	for _, tt := range tests {
+		if got := RedactSecret(tt.in); got != tt.want {
+			t.Errorf("RedactSecret(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,471 @@
+package ncp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// knownKeys are the configure file keys nks-ctx understands. Other keys are
+// reported as warnings, which catches typos such as "ncloud_secret_key".
+var knownKeys = map[string]bool{
+	"ncloud_access_key_id":     true,
+	"ncloud_secret_access_key": true,
+	"ncloud_api_url":           true,
+	"ncloud_region":            true,
+	"ncloud_session_token":     true,
+	"credential_process":       true,
+	"source_profile":           true,
+	"include":                  true,
+}
+
+// ConfigFile is a parsed, editable view of ~/.ncloud/configure.
+//
+// The parser accepts:
+//
+//	[DEFAULT]                 section header
+//	[profile finance]         AWS-style header, same as [finance]
+//	key = value               whitespace around "=" is ignored
+//	key = "quoted # value"    single or double quotes; \" and \\ escapes in double quotes
+//	key = value  # comment    inline comments need whitespace before # or ;
+//	key = first \             a trailing backslash continues the value
+//	      second
+//	source_profile = DEFAULT  inherit keys the profile does not set (alias: include)
+//
+// Lines that are not modified are written back byte-for-byte, so comments,
+// blank lines and ordering survive a round trip.
+type ConfigFile struct {
+	path     string
+	lines    []iniLine
+	warnings []string
+	errors   []sectionError
+}
+
+// sectionError is a LineError and the section the malformed line is in.
+type sectionError struct {
+	section string
+	LineError
+}
+
+// brokenSection holds the lines after a malformed section header, up to the
+// next valid one. It is not a profile, so its keys are never used.
+const brokenSection = ""
+
+type iniLine struct {
+	raw     string // original text; continued values span several lines
+	lineNo  int
+	section string
+	key     string
+	value   string
+	header  bool
+}
+
+// LineError is a syntax error at a line of the configure file.
+type LineError struct {
+	Line int
+	Msg  string
+}
+
+// SyntaxError lists every malformed line found while parsing a configure file.
+type SyntaxError struct {
+	Path   string
+	Errors []LineError
+}
+
+func (e *SyntaxError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, le := range e.Errors {
+		msgs = append(msgs, fmt.Sprintf("%s:%d: %s", e.Path, le.Line, le.Msg))
+	}
+	return "syntax error in configure file:\n  " + strings.Join(msgs, "\n  ")
+}
+
+// DefaultConfigPath returns the path of the NCP configure file (~/.ncloud/configure).
+func DefaultConfigPath() string {
+	return configFilePath()
+}
+
+// OpenConfigFile parses path. A missing file yields an empty ConfigFile;
+// malformed lines anywhere in the file yield a *SyntaxError.
+func OpenConfigFile(path string) (*ConfigFile, error) {
+	f, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.syntaxError(func(string) bool { return true }); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// readConfigFile parses path, keeping malformed lines as they are and
+// recording them for Resolve and syntaxError.
+func readConfigFile(path string) (*ConfigFile, error) {
+	f := &ConfigFile{path: path}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return f, nil
+		}
+		return nil, err
+	}
+	f.parse(string(raw))
+	return f, nil
+}
+
+// syntaxError returns a *SyntaxError listing the malformed lines in the
+// sections include accepts, or nil if there are none.
+func (f *ConfigFile) syntaxError(include func(section string) bool) error {
+	var errs []LineError
+	for _, e := range f.errors {
+		if include(e.section) {
+			errs = append(errs, e.LineError)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &SyntaxError{Path: f.path, Errors: errs}
+}
+
+func (f *ConfigFile) parse(text string) {
+	text = strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if text == "" {
+		return
+	}
+
+	firstSeen := make(map[string]int)
+	section := "DEFAULT"
+	rawLines := strings.Split(text, "\n")
+
+	for i := 0; i < len(rawLines); i++ {
+		l := iniLine{raw: rawLines[i], lineNo: i + 1, section: section}
+		trimmed := strings.TrimSpace(l.raw)
+
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";"):
+
+		case strings.HasPrefix(trimmed, "["):
+			name, err := parseHeader(trimmed)
+			if err != nil {
+				section = brokenSection
+				l.section = section
+				f.errors = append(f.errors, sectionError{section, LineError{l.lineNo, err.Error()}})
+				break
+			}
+			section = name
+			l.section = name
+			l.header = true
+
+		default:
+			logical := trimmed
+			for strings.HasSuffix(logical, `\`) && i+1 < len(rawLines) {
+				i++
+				l.raw += "\n" + rawLines[i]
+				logical = strings.TrimSuffix(logical, `\`) + strings.TrimSpace(rawLines[i])
+			}
+
+			key, value, err := parseAssignment(logical)
+			if err != nil {
+				f.errors = append(f.errors, sectionError{section, LineError{l.lineNo, err.Error()}})
+				break
+			}
+			l.key, l.value = key, value
+
+			id := section + "\x00" + key
+			if prev, ok := firstSeen[id]; ok {
+				f.warnings = append(f.warnings, fmt.Sprintf("%s:%d: duplicate key %q in [%s] overrides line %d", f.path, l.lineNo, key, section, prev))
+			}
+			firstSeen[id] = l.lineNo
+			if !knownKeys[key] {
+				f.warnings = append(f.warnings, fmt.Sprintf("%s:%d: unknown key %q in [%s]", f.path, l.lineNo, key, section))
+			}
+		}
+		f.lines = append(f.lines, l)
+	}
+
+	// Comments directly above a header document that section, so they move
+	// with it when sections are edited or removed.
+	for i, l := range f.lines {
+		if !l.header {
+			continue
+		}
+		for j := i - 1; j >= 0 && isComment(f.lines[j]); j-- {
+			f.lines[j].section = l.section
+		}
+	}
+}
+
+func parseHeader(line string) (string, error) {
+	end := strings.Index(line, "]")
+	if end < 0 {
+		return "", fmt.Errorf("unterminated section header %q", line)
+	}
+	if rest := strings.TrimSpace(line[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") && !strings.HasPrefix(rest, ";") {
+		return "", fmt.Errorf("unexpected text after section header: %q", rest)
+	}
+	name := strings.TrimSpace(line[1:end])
+	if fields := strings.Fields(name); len(fields) == 2 && fields[0] == "profile" {
+		name = fields[1]
+	}
+	if name == "" {
+		return "", fmt.Errorf("empty section name")
+	}
+	return name, nil
+}
+
+func parseAssignment(line string) (string, string, error) {
+	idx := strings.Index(line, "=")
+	if idx < 0 {
+		return "", "", fmt.Errorf("expected key=value, got %q", line)
+	}
+	key := strings.TrimSpace(line[:idx])
+	if key == "" {
+		return "", "", fmt.Errorf("missing key before '='")
+	}
+	if strings.ContainsAny(key, " \t") {
+		return "", "", fmt.Errorf("invalid key %q", key)
+	}
+	value, err := parseValue(strings.TrimSpace(line[idx+1:]))
+	if err != nil {
+		return "", "", err
+	}
+	return key, value, nil
+}
+
+func parseValue(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+
+	if q := s[0]; q == '"' || q == '\'' {
+		var b strings.Builder
+		for i := 1; i < len(s); i++ {
+			c := s[i]
+			if q == '"' && c == '\\' && i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+				continue
+			}
+			if c == q {
+				rest := strings.TrimSpace(s[i+1:])
+				if rest != "" && !strings.HasPrefix(rest, "#") && !strings.HasPrefix(rest, ";") {
+					return "", fmt.Errorf("unexpected text after quoted value: %q", rest)
+				}
+				return b.String(), nil
+			}
+			b.WriteByte(c)
+		}
+		return "", fmt.Errorf("unterminated quoted value")
+	}
+
+	for i := 1; i < len(s); i++ {
+		if (s[i] == '#' || s[i] == ';') && (s[i-1] == ' ' || s[i-1] == '\t') {
+			return strings.TrimSpace(s[:i]), nil
+		}
+	}
+	return s, nil
+}
+
+// formatValue quotes value when it would not survive parseValue unquoted.
+func formatValue(value string) string {
+	if value == strings.TrimSpace(value) && !strings.ContainsAny(value, "#;\"'\\") {
+		return value
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+func isComment(l iniLine) bool {
+	trimmed := strings.TrimSpace(l.raw)
+	return strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";")
+}
+
+// Path returns the file path the ConfigFile was read from.
+func (f *ConfigFile) Path() string {
+	return f.path
+}
+
+// Warnings returns non-fatal problems found while parsing, such as unknown
+// or duplicate keys, prefixed with file:line.
+func (f *ConfigFile) Warnings() []string {
+	return f.warnings
+}
+
+// Profiles returns profile names in file order.
+func (f *ConfigFile) Profiles() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, l := range f.lines {
+		if (l.header || l.key != "") && l.section != brokenSection && !seen[l.section] {
+			seen[l.section] = true
+			names = append(names, l.section)
+		}
+	}
+	return names
+}
+
+// HasProfile reports whether the file defines profile.
+func (f *ConfigFile) HasProfile(profile string) bool {
+	for _, name := range f.Profiles() {
+		if name == profile {
+			return true
+		}
+	}
+	return false
+}
+
+// Profile returns the key/value pairs set directly in a profile.
+// Later assignments of a duplicate key win.
+func (f *ConfigFile) Profile(profile string) (map[string]string, bool) {
+	if !f.HasProfile(profile) {
+		return nil, false
+	}
+	data := make(map[string]string)
+	for _, l := range f.lines {
+		if l.section == profile && l.key != "" {
+			data[l.key] = l.value
+		}
+	}
+	return data, true
+}
+
+// Resolve returns a profile's key/value pairs with keys it does not set
+// inherited from its source_profile (or include) chain. Malformed lines in
+// the chain's sections yield a *SyntaxError; those elsewhere are ignored.
+func (f *ConfigFile) Resolve(profile string) (map[string]string, error) {
+	resolved := make(map[string]string)
+	visited := make(map[string]bool)
+	var chain []string
+
+	for name := profile; name != ""; {
+		if visited[name] {
+			return nil, fmt.Errorf("source_profile cycle in %s: %s -> %s", f.path, strings.Join(chain, " -> "), name)
+		}
+		visited[name] = true
+		chain = append(chain, name)
+
+		data, ok := f.Profile(name)
+		if !ok {
+			// The profile may be behind a malformed header.
+			if err := f.syntaxError(func(s string) bool { return s == brokenSection }); err != nil {
+				return nil, err
+			}
+			if name == profile {
+				return nil, fmt.Errorf("profile '%s' not found in %s", profile, f.path)
+			}
+			return nil, fmt.Errorf("profile '%s' inherits from missing profile '%s' in %s", chain[len(chain)-2], name, f.path)
+		}
+		for k, v := range data {
+			if _, set := resolved[k]; !set {
+				resolved[k] = v
+			}
+		}
+
+		name = data["source_profile"]
+		if name == "" {
+			name = data["include"]
+		}
+	}
+
+	if err := f.syntaxError(func(s string) bool { return visited[s] }); err != nil {
+		return nil, err
+	}
+	delete(resolved, "source_profile")
+	delete(resolved, "include")
+	return resolved, nil
+}
+
+// Set assigns key=value in profile, replacing an existing assignment in place
+// or appending it to the end of the section. Missing sections are created.
+func (f *ConfigFile) Set(profile, key, value string) {
+	line := iniLine{raw: key + "=" + formatValue(value), section: profile, key: key, value: value}
+
+	for i := len(f.lines) - 1; i >= 0; i-- {
+		if f.lines[i].section == profile && f.lines[i].key == key {
+			f.lines[i] = line
+			return
+		}
+	}
+
+	if insertAt := f.sectionEnd(profile); insertAt >= 0 {
+		f.lines = append(f.lines[:insertAt], append([]iniLine{line}, f.lines[insertAt:]...)...)
+		return
+	}
+
+	if len(f.lines) > 0 && strings.TrimSpace(f.lines[len(f.lines)-1].raw) != "" {
+		f.lines = append(f.lines, iniLine{section: profile})
+	}
+	f.lines = append(f.lines, iniLine{raw: "[" + profile + "]", section: profile, header: true}, line)
+}
+
+// Unset removes key from profile.
+func (f *ConfigFile) Unset(profile, key string) {
+	kept := f.lines[:0]
+	for _, l := range f.lines {
+		if l.section == profile && l.key == key {
+			continue
+		}
+		kept = append(kept, l)
+	}
+	f.lines = kept
+}
+
+// RemoveProfile deletes a profile's header and entries, and reports whether it existed.
+// Comments and blank lines inside the section are removed with it.
+func (f *ConfigFile) RemoveProfile(profile string) bool {
+	if !f.HasProfile(profile) {
+		return false
+	}
+	kept := f.lines[:0]
+	for _, l := range f.lines {
+		if l.section == profile {
+			continue
+		}
+		kept = append(kept, l)
+	}
+	for len(kept) > 0 && strings.TrimSpace(kept[len(kept)-1].raw) == "" {
+		kept = kept[:len(kept)-1]
+	}
+	f.lines = kept
+	return true
+}
+
+// Save writes the file atomically with 0600 permissions.
+func (f *ConfigFile) Save() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	var b strings.Builder
+	for _, l := range f.lines {
+		b.WriteString(l.raw)
+		b.WriteString("\n")
+	}
+
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0600); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, 0600); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
+
+// sectionEnd returns the index just past the last non-blank line of profile,
+// or -1 if the profile does not exist.
+func (f *ConfigFile) sectionEnd(profile string) int {
+	end := -1
+	for i, l := range f.lines {
+		if l.section != profile {
+			continue
+		}
+		if l.header || strings.TrimSpace(l.raw) != "" {
+			end = i + 1
+		}
+	}
+	return end
+}
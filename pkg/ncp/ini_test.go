@@ -0,0 +1,346 @@
+package ncp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sampleConfigure = `# NCP credentials
+[DEFAULT]
+ncloud_access_key_id=default-ak
+ncloud_secret_access_key=default-sk
+
+# finance account, do not share
+[finance]
+ncloud_access_key_id = fin-ak
+ncloud_secret_access_key = fin-sk
+ncloud_api_url = https://fin-ncloud.apigw.fin-ntruss.com
+`
+
+func writeSampleConfigure(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "configure")
+	if err := os.WriteFile(path, []byte(sampleConfigure), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigFile_RoundTrip(t *testing.T) {
+	path := writeSampleConfigure(t)
+
+	f, err := OpenConfigFile(path)
+	if err != nil {
+		t.Fatalf("OpenConfigFile() error = %v", err)
+	}
+	if err := f.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	got, _ := os.ReadFile(path)
+	if string(got) != sampleConfigure {
+		t.Errorf("round trip changed file:\n%s", got)
+	}
+
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestConfigFile_Edit(t *testing.T) {
+	path := writeSampleConfigure(t)
+
+	f, err := OpenConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := f.Profiles(); len(got) != 2 || got[0] != "DEFAULT" || got[1] != "finance" {
+		t.Fatalf("Profiles() = %v, want [DEFAULT finance]", got)
+	}
+
+	f.Set("DEFAULT", "ncloud_region", "KR")
+	f.Set("finance", "ncloud_access_key_id", "new-fin-ak")
+	f.Set("gov", "ncloud_access_key_id", "gov-ak")
+	if err := f.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# NCP credentials
+[DEFAULT]
+ncloud_access_key_id=default-ak
+ncloud_secret_access_key=default-sk
+ncloud_region=KR
+
+# finance account, do not share
+[finance]
+ncloud_access_key_id=new-fin-ak
+ncloud_secret_access_key = fin-sk
+ncloud_api_url = https://fin-ncloud.apigw.fin-ntruss.com
+
+[gov]
+ncloud_access_key_id=gov-ak
+`
+	got, _ := os.ReadFile(path)
+	if string(got) != want {
+		t.Errorf("after Set():\n%s\nwant:\n%s", got, want)
+	}
+
+	data, ok := f.Profile("finance")
+	if !ok || data["ncloud_secret_access_key"] != "fin-sk" {
+		t.Errorf("Profile(finance) = %v, %v", data, ok)
+	}
+}
+
+func TestConfigFile_RemoveProfile(t *testing.T) {
+	path := writeSampleConfigure(t)
+
+	f, err := OpenConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.RemoveProfile("finance") {
+		t.Fatal("RemoveProfile(finance) = false")
+	}
+	if f.RemoveProfile("missing") {
+		t.Error("RemoveProfile(missing) = true")
+	}
+	if err := f.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# NCP credentials
+[DEFAULT]
+ncloud_access_key_id=default-ak
+ncloud_secret_access_key=default-sk
+`
+	got, _ := os.ReadFile(path)
+	if string(got) != want {
+		t.Errorf("after RemoveProfile():\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestConfigFile_Syntax(t *testing.T) {
+	content := `[profile finance]
+ncloud_access_key_id = "fin ak # not a comment"
+ncloud_secret_access_key = fin-sk   # inline comment
+ncloud_api_url = https://fin-ncloud.apigw.fin-ntruss.com;v=1
+credential_process = /usr/bin/fetch \
+    --profile finance
+ncloud_region = 'KR'
+ncloud_region = JPN
+ncloud_secert_access_key = typo
+`
+	path := filepath.Join(t.TempDir(), "configure")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := OpenConfigFile(path)
+	if err != nil {
+		t.Fatalf("OpenConfigFile() error = %v", err)
+	}
+
+	data, ok := f.Profile("finance")
+	if !ok {
+		t.Fatal("Profile(finance) not found for [profile finance] header")
+	}
+
+	want := map[string]string{
+		"ncloud_access_key_id":     "fin ak # not a comment",
+		"ncloud_secret_access_key": "fin-sk",
+		"ncloud_api_url":           "https://fin-ncloud.apigw.fin-ntruss.com;v=1",
+		"credential_process":       "/usr/bin/fetch --profile finance",
+		"ncloud_region":            "JPN",
+	}
+	for k, v := range want {
+		if data[k] != v {
+			t.Errorf("%s = %q, want %q", k, data[k], v)
+		}
+	}
+
+	warnings := f.Warnings()
+	if len(warnings) != 2 {
+		t.Fatalf("Warnings() = %v, want duplicate and unknown key warnings", warnings)
+	}
+	if !strings.Contains(warnings[0], ":8: duplicate key") {
+		t.Errorf("warnings[0] = %q, want line 8 duplicate", warnings[0])
+	}
+	if !strings.Contains(warnings[1], ":9: unknown key") {
+		t.Errorf("warnings[1] = %q, want line 9 unknown key", warnings[1])
+	}
+
+	// Unmodified continuation lines are written back as-is.
+	if err := f.Save(); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(path)
+	if string(got) != content {
+		t.Errorf("round trip changed file:\n%s", got)
+	}
+}
+
+func TestConfigFile_SyntaxErrors(t *testing.T) {
+	content := `[DEFAULT
+ncloud_access_key_id
+ncloud_secret_access_key = "unterminated
+`
+	path := filepath.Join(t.TempDir(), "configure")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := OpenConfigFile(path)
+	synErr, ok := err.(*SyntaxError)
+	if !ok {
+		t.Fatalf("OpenConfigFile() error = %v, want *SyntaxError", err)
+	}
+	if len(synErr.Errors) != 3 {
+		t.Fatalf("errors = %v, want 3", synErr.Errors)
+	}
+	for i, le := range synErr.Errors {
+		if le.Line != i+1 {
+			t.Errorf("errors[%d].Line = %d, want %d", i, le.Line, i+1)
+		}
+	}
+}
+
+func TestLoadFromFile_SyntaxErrorInOtherProfile(t *testing.T) {
+	content := `[base]
+ncloud_api_url = https://fin-ncloud.apigw.fin-ntruss.com
+
+[team-a]
+source_profile = base
+ncloud_access_key_id = a-ak
+ncloud_secret_access_key = a-sk
+
+[broken]
+source_profile = base
+ncloud_access_key_id
+ncloud_secret_access_key = b-sk
+
+[typo
+ncloud_access_key_id = c-ak
+`
+	path := filepath.Join(t.TempDir(), "configure")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := loadFromFile(path, "team-a")
+	if err != nil {
+		t.Fatalf("loadFromFile(team-a) error = %v", err)
+	}
+	if cfg.AccessKey != "a-ak" || cfg.ApiURL != "https://fin-ncloud.apigw.fin-ntruss.com" {
+		t.Errorf("loadFromFile(team-a) = %+v", cfg)
+	}
+
+	_, err = loadFromFile(path, "broken")
+	if synErr, ok := err.(*SyntaxError); !ok || len(synErr.Errors) != 1 || synErr.Errors[0].Line != 11 {
+		t.Errorf("loadFromFile(broken) error = %v, want *SyntaxError at line 11", err)
+	}
+	// A profile behind a malformed header reports the header, not "not found".
+	_, err = loadFromFile(path, "typo")
+	if synErr, ok := err.(*SyntaxError); !ok || synErr.Errors[0].Line != 14 {
+		t.Errorf("loadFromFile(typo) error = %v, want *SyntaxError at line 14", err)
+	}
+	if _, err := OpenConfigFile(path); err == nil {
+		t.Error("OpenConfigFile() accepted a file with malformed lines")
+	}
+}
+
+func TestDefaultChain_ReturnsSyntaxWarnings(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	content := `[team-a]
+ncloud_access_key_id = a-ak
+ncloud_secret_access_key = a-sk
+
+[broken]
+ncloud_access_key_id
+`
+	path := filepath.Join(home, ".ncloud", "configure")
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := DefaultChain().Without("env").Retrieve("team-a")
+	if err != nil {
+		t.Fatalf("Retrieve(team-a) error = %v", err)
+	}
+	if len(cfg.Warnings) != 1 || !strings.HasPrefix(cfg.Warnings[0], path+":6: ") {
+		t.Errorf("Retrieve(team-a) Warnings = %q, want one for %s:6", cfg.Warnings, path)
+	}
+}
+
+func TestConfigFile_Resolve(t *testing.T) {
+	content := `[base]
+ncloud_api_url = https://fin-ncloud.apigw.fin-ntruss.com
+ncloud_region = KR
+
+[team-a]
+source_profile = base
+ncloud_access_key_id = a-ak
+ncloud_secret_access_key = a-sk
+
+[team-b]
+include = team-a
+ncloud_region = JPN
+
+[loop-1]
+source_profile = loop-2
+
+[loop-2]
+source_profile = loop-1
+
+[orphan]
+source_profile = missing
+`
+	path := filepath.Join(t.TempDir(), "configure")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := OpenConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := f.Resolve("team-b")
+	if err != nil {
+		t.Fatalf("Resolve(team-b) error = %v", err)
+	}
+	if data["ncloud_region"] != "JPN" {
+		t.Errorf("region = %q, want JPN (own value wins)", data["ncloud_region"])
+	}
+	if data["ncloud_api_url"] != "https://fin-ncloud.apigw.fin-ntruss.com" {
+		t.Errorf("api url = %q, want inherited from base", data["ncloud_api_url"])
+	}
+	if data["ncloud_access_key_id"] != "a-ak" {
+		t.Errorf("access key = %q, want inherited from team-a", data["ncloud_access_key_id"])
+	}
+	if _, ok := data["source_profile"]; ok {
+		t.Error("Resolve() leaked source_profile")
+	}
+
+	if _, err := f.Resolve("loop-1"); err == nil {
+		t.Error("Resolve(loop-1) expected cycle error")
+	}
+	if _, err := f.Resolve("orphan"); err == nil {
+		t.Error("Resolve(orphan) expected missing parent error")
+	}
+
+	cfg, err := loadFromFile(path, "team-a")
+	if err != nil {
+		t.Fatalf("loadFromFile(team-a) error = %v", err)
+	}
+	if cfg.Region != "KR" {
+		t.Errorf("Region = %q, want KR from base", cfg.Region)
+	}
+}
@@ -0,0 +1,109 @@
+package ncp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/consol-lee/nks-ctx/pkg/state"
+)
+
+// Inventory caches the clusters last listed by sync, so they can be shown
+// without calling the NKS API.
+type Inventory struct {
+	path string
+
+	// Clusters maps cluster UUIDs to what was last listed.
+	Clusters map[string]InventoryEntry `json:"clusters"`
+}
+
+// InventoryEntry is one cached cluster.
+type InventoryEntry struct {
+	Name    string    `json:"name"`
+	Region  string    `json:"region"`
+	Status  string    `json:"status"`
+	Profile string    `json:"profile"`
+	Listed  time.Time `json:"listed"`
+}
+
+// DefaultInventoryPath returns the inventory cache file path.
+func DefaultInventoryPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "nks-ctx", "inventory.json")
+}
+
+// LoadInventory reads the inventory at path. Missing or unreadable files
+// yield an empty inventory.
+func LoadInventory(path string) *Inventory {
+	inv := &Inventory{path: path}
+	if raw, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(raw, inv)
+	}
+	if inv.Clusters == nil {
+		inv.Clusters = make(map[string]InventoryEntry)
+	}
+	return inv
+}
+
+// UpdateInventory replaces the cached clusters of profile in the inventory at
+// path. It holds the file's lock for the whole load-modify-save, so concurrent
+// syncs of different profiles do not drop each other's clusters.
+func UpdateInventory(path, profile string, clusters []Cluster, at time.Time) error {
+	unlock, err := state.Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	inv := LoadInventory(path)
+	inv.Update(profile, clusters, at)
+	return inv.Save()
+}
+
+// Update replaces the cached clusters of profile with clusters.
+func (inv *Inventory) Update(profile string, clusters []Cluster, at time.Time) {
+	for uuid, e := range inv.Clusters {
+		if e.Profile == profile {
+			delete(inv.Clusters, uuid)
+		}
+	}
+	for _, c := range clusters {
+		inv.Clusters[c.UUID] = InventoryEntry{Name: c.Name, Region: c.Region, Status: c.Status, Profile: profile, Listed: at}
+	}
+}
+
+// Get returns the cached cluster with uuid.
+func (inv *Inventory) Get(uuid string) (InventoryEntry, bool) {
+	e, ok := inv.Clusters[uuid]
+	return e, ok
+}
+
+// Save writes the inventory through a temporary file, so concurrent readers
+// such as the prompt never see a partial write. Use UpdateInventory to change
+// the file safely while other processes may write it.
+func (inv *Inventory) Save() error {
+	raw, err := json.MarshalIndent(inv, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(inv.path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(inv.path), ".inventory-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), inv.path)
+}
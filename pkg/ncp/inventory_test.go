@@ -0,0 +1,61 @@
+package ncp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInventory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nks-ctx", "inventory.json")
+	inv := LoadInventory(path)
+	if _, ok := inv.Get("u1"); ok {
+		t.Error("Get() on empty inventory = ok")
+	}
+
+	at := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	inv.Update("DEFAULT", []Cluster{{UUID: "u1", Name: "prod", Region: "KR", Status: "RUNNING"}, {UUID: "u2", Name: "old"}}, at)
+	inv.Update("finance", []Cluster{{UUID: "u3", Name: "fin", Region: "KR", Status: "RUNNING"}}, at)
+	// A later sync of the same profile drops clusters that are gone.
+	inv.Update("DEFAULT", []Cluster{{UUID: "u1", Name: "prod", Region: "KR", Status: "UPGRADING"}}, at)
+	if err := inv.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := LoadInventory(path)
+	if e, ok := loaded.Get("u1"); !ok || e.Status != "UPGRADING" || e.Profile != "DEFAULT" || !e.Listed.Equal(at) {
+		t.Errorf("Get(u1) = %+v, %v", e, ok)
+	}
+	if _, ok := loaded.Get("u2"); ok {
+		t.Error("Get(u2) found a cluster removed by a later sync")
+	}
+	if _, ok := loaded.Get("u3"); !ok {
+		t.Error("Get(u3) lost the cluster of another profile")
+	}
+}
+
+func TestUpdateInventory_Concurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nks-ctx", "inventory.json")
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			profile := fmt.Sprintf("profile-%d", i)
+			if err := UpdateInventory(path, profile, []Cluster{{UUID: "uuid-" + profile}}, time.Now()); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if n := len(LoadInventory(path).Clusters); n != 20 {
+		t.Errorf("len(Clusters) = %d, want 20: concurrent updates were lost", n)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 2 {
+		t.Errorf("files next to the inventory = %v, want it and its lock", entries)
+	}
+}
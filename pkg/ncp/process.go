@@ -0,0 +1,123 @@
+package ncp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultAuthenticatorTimeout bounds a single ncp-iam-authenticator invocation.
+const DefaultAuthenticatorTimeout = 30 * time.Second
+
+// AuthenticatorError is a failed ncp-iam-authenticator invocation.
+type AuthenticatorError struct {
+	Command  string // subcommand, e.g. "update-kubeconfig"
+	ExitCode int    // -1 if the process was killed or did not start
+	Stderr   string
+	TimedOut bool
+	Err      error
+}
+
+func (e *AuthenticatorError) Error() string {
+	var msg string
+	switch {
+	case e.TimedOut:
+		msg = fmt.Sprintf("ncp-iam-authenticator %s timed out", e.Command)
+	case errors.Is(e.Err, context.Canceled):
+		msg = fmt.Sprintf("ncp-iam-authenticator %s cancelled", e.Command)
+	case e.ExitCode >= 0:
+		msg = fmt.Sprintf("ncp-iam-authenticator %s failed (exit code %d)", e.Command, e.ExitCode)
+	default:
+		msg = fmt.Sprintf("ncp-iam-authenticator %s failed: %v", e.Command, e.Err)
+	}
+	if e.Stderr != "" {
+		msg += ": " + e.Stderr
+	}
+	return msg
+}
+
+func (e *AuthenticatorError) Unwrap() error {
+	return e.Err
+}
+
+// Reason returns a one-line cause suitable for a summary table: the last
+// line the authenticator wrote to stderr, or the failure kind.
+func (e *AuthenticatorError) Reason() string {
+	lines := strings.Split(e.Stderr, "\n")
+	if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+		return last
+	}
+	switch {
+	case e.TimedOut:
+		return "timed out"
+	case errors.Is(e.Err, context.Canceled):
+		return "cancelled"
+	case e.ExitCode >= 0:
+		return fmt.Sprintf("exit code %d", e.ExitCode)
+	}
+	return e.Err.Error()
+}
+
+// run executes the authenticator in its own process group with the
+// Authenticator's timeout. When ctx is cancelled or the timeout expires the
+// whole group is killed, so helpers it started do not linger. Stderr is
+// captured for the returned *AuthenticatorError and, if stderr is non-nil,
+// also passed through.
+func (a *Authenticator) run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	env, err := a.environ()
+	if err != nil {
+		return err
+	}
+
+	timeout := a.Timeout
+	if timeout == 0 {
+		timeout = DefaultAuthenticatorTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var errBuf bytes.Buffer
+	cmd := exec.CommandContext(ctx, a.binaryPath, args...)
+	cmd.Env = env
+	cmd.Stdout = stdout
+	cmd.Stderr = &errBuf
+	if stderr != nil {
+		cmd.Stderr = io.MultiWriter(&errBuf, stderr)
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	if err == nil {
+		return nil
+	}
+
+	aerr := &AuthenticatorError{
+		Command:  args[0],
+		ExitCode: -1,
+		Stderr:   strings.TrimSpace(errBuf.String()),
+		Err:      err,
+	}
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		aerr.TimedOut = true
+		aerr.Err = ctx.Err()
+	case context.Canceled:
+		aerr.Err = ctx.Err()
+	default:
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			aerr.ExitCode = exitErr.ExitCode()
+		}
+	}
+	return aerr
+}
@@ -0,0 +1,116 @@
+package ncp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxSkew is how far the signature timestamp may drift from the API gateway's
+// clock before requests are rejected.
+const MaxSkew = 5 * time.Minute
+
+// SkewStore persists the clock offset measured against each API gateway host,
+// so later invocations sign with a corrected timestamp from the first request.
+type SkewStore struct {
+	path string
+
+	mu      sync.Mutex
+	Offsets map[string]SkewEntry `json:"offsets"`
+}
+
+// SkewEntry is the offset (server time minus local time) measured for a host.
+type SkewEntry struct {
+	OffsetMillis int64     `json:"offsetMillis"`
+	Measured     time.Time `json:"measured"`
+}
+
+// Offset returns the entry's offset as a Duration.
+func (e SkewEntry) Offset() time.Duration {
+	return time.Duration(e.OffsetMillis) * time.Millisecond
+}
+
+// DefaultSkewStorePath returns the clock offset cache file path.
+func DefaultSkewStorePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "nks-ctx", "clock-skew.json")
+}
+
+// LoadSkewStore reads the store at path. Missing or unreadable files yield an empty store.
+func LoadSkewStore(path string) *SkewStore {
+	s := &SkewStore{path: path, Offsets: make(map[string]SkewEntry)}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return s
+	}
+	if err := json.Unmarshal(raw, s); err != nil || s.Offsets == nil {
+		s.Offsets = make(map[string]SkewEntry)
+	}
+	return s
+}
+
+// Offset returns the stored offset for host, or 0. A nil store has no offsets.
+func (s *SkewStore) Offset(host string) time.Duration {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Offsets[host].Offset()
+}
+
+// Set records the offset for host and writes the store to disk.
+func (s *SkewStore) Set(host string, offset time.Duration) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Offsets[host] = SkewEntry{OffsetMillis: offset.Milliseconds(), Measured: time.Now()}
+
+	raw, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, raw, 0600)
+}
+
+// serverOffset computes server time minus local time from the response Date
+// header, using the midpoint of the request as the local reference.
+func serverOffset(resp *http.Response, sent, received time.Time) (time.Duration, bool) {
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return 0, false
+	}
+	local := sent.Add(received.Sub(sent) / 2)
+	return date.Sub(local), true
+}
+
+// isTimestampRejection reports whether the gateway refused a request because
+// of its signature timestamp.
+func isTimestampRejection(resp *http.Response, body []byte) bool {
+	if resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden {
+		return false
+	}
+	return strings.Contains(strings.ToLower(string(body)), "timestamp")
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Host
+}
@@ -0,0 +1,131 @@
+package ncp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// skewedServer rejects timestamps more than MaxSkew away from a clock that
+// runs ahead of the local one by skew.
+func skewedServer(t *testing.T, skew time.Duration, requests *int) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		now := time.Now().Add(skew)
+		w.Header().Set("Date", now.UTC().Format(http.TimeFormat))
+
+		ms, _ := strconv.ParseInt(r.Header.Get("x-ncp-apigw-timestamp"), 10, 64)
+		if absDuration(now.Sub(time.UnixMilli(ms))) > MaxSkew {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"errorCode":"210","message":"Invalid timestamp"}}`))
+			return
+		}
+		json.NewEncoder(w).Encode(clusterListResponse{Clusters: []clusterInfo{{UUID: "u1", Name: "c1"}}})
+	}))
+}
+
+func TestClient_RetriesWithMeasuredSkew(t *testing.T) {
+	var requests int
+	server := skewedServer(t, 10*time.Minute, &requests)
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "clock-skew.json")
+	client := &Client{
+		accessKey:   "ak",
+		secretKey:   "sk",
+		nksBaseURLs: []string{server.URL},
+		skew:        LoadSkewStore(path),
+	}
+
+	clusters, err := client.ListClusters()
+	if err != nil {
+		t.Fatalf("ListClusters() error = %v", err)
+	}
+	if len(clusters) != 1 || requests != 2 {
+		t.Fatalf("clusters = %d, requests = %d; want 1 cluster after 2 requests", len(clusters), requests)
+	}
+
+	stored := LoadSkewStore(path).Offset(hostOf(server.URL))
+	if absDuration(stored-10*time.Minute) > 2*time.Second {
+		t.Errorf("stored offset = %v, want about 10m", stored)
+	}
+
+	// A new client reads the stored offset and signs correctly the first time.
+	requests = 0
+	client.skew = LoadSkewStore(path)
+	if _, err := client.ListClusters(); err != nil {
+		t.Fatalf("second ListClusters() error = %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("requests = %d, want 1 with stored offset", requests)
+	}
+}
+
+func TestClient_NoRetryWithoutSkew(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Date", time.Now().UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":{"message":"Invalid timestamp"}}`))
+	}))
+	defer server.Close()
+
+	client := &Client{
+		accessKey:   "ak",
+		secretKey:   "sk",
+		nksBaseURLs: []string{server.URL},
+		skew:        LoadSkewStore(filepath.Join(t.TempDir(), "clock-skew.json")),
+	}
+
+	if _, err := client.ListClusters(); err == nil {
+		t.Fatal("ListClusters() expected error, got nil")
+	}
+	if requests != 1 {
+		t.Errorf("requests = %d, want 1 when clocks agree", requests)
+	}
+}
+
+func TestClient_MeasureSkew(t *testing.T) {
+	var requests int
+	server := skewedServer(t, -3*time.Minute, &requests)
+	defer server.Close()
+
+	client := &Client{
+		accessKey:   "ak",
+		secretKey:   "sk",
+		nksBaseURLs: []string{server.URL + "/a", server.URL + "/b"},
+	}
+
+	reports, err := client.MeasureSkew()
+	if err != nil {
+		t.Fatalf("MeasureSkew() error = %v", err)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("reports = %d, want 1 per host", len(reports))
+	}
+	if absDuration(reports[0].Offset+3*time.Minute) > 2*time.Second {
+		t.Errorf("offset = %v, want about -3m", reports[0].Offset)
+	}
+	if skew := reports[0].Skew(); absDuration(skew-3*time.Minute) > 2*time.Second {
+		t.Errorf("Skew() = %v, want about 3m", skew)
+	}
+	if residual := (SkewReport{Offset: -3 * time.Minute, Stored: -2 * time.Minute}).Residual(); residual != time.Minute {
+		t.Errorf("Residual() = %v, want 1m", residual)
+	}
+}
+
+func TestSkewStore_Nil(t *testing.T) {
+	var s *SkewStore
+	if s.Offset("host") != 0 {
+		t.Error("nil store should have no offset")
+	}
+	if err := s.Set("host", time.Minute); err != nil {
+		t.Errorf("nil store Set() error = %v", err)
+	}
+}
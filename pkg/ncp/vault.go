@@ -0,0 +1,107 @@
+package ncp
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/consol-lee/nks-ctx/pkg/vault"
+)
+
+// VaultProvider reads keys from the encrypted vault written by
+// `nks-ctx credentials store`. Unlocked keys are cached in the credentials
+// agent for Timeout so repeated invocations (e.g. kubectl token calls) do not prompt.
+type VaultProvider struct {
+	Path    string
+	Agent   *vault.AgentClient
+	Timeout time.Duration
+	// Prompt asks for the vault passphrase. If nil, a locked vault is an error.
+	Prompt func(profile string) (string, error)
+	// ConfigPath is consulted for ncloud_api_url and ncloud_region.
+	ConfigPath string
+
+	source *configSource
+}
+
+// NewVaultProvider returns a VaultProvider using the default vault, agent
+// socket and terminal prompt. NKS_CTX_VAULT_TIMEOUT overrides the cache timeout.
+func NewVaultProvider(configPath string) *VaultProvider {
+	timeout := vault.DefaultTimeout
+	if v := os.Getenv("NKS_CTX_VAULT_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			timeout = d
+		}
+	}
+	return &VaultProvider{
+		Path:       vault.DefaultPath(),
+		Agent:      vault.NewAgentClient(vault.DefaultSocketPath()),
+		Timeout:    timeout,
+		Prompt:     promptPassphrase,
+		ConfigPath: configPath,
+	}
+}
+
+// Name implements CredentialsProvider.
+func (p *VaultProvider) Name() string {
+	return "vault"
+}
+
+// Retrieve implements CredentialsProvider.
+func (p *VaultProvider) Retrieve(profile string) (*Config, error) {
+	profile = profileOrDefault(profile)
+
+	v, err := vault.Open(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !v.Has(profile) {
+		return nil, fmt.Errorf("profile '%s' not stored in %s", profile, p.Path)
+	}
+
+	creds, err := p.unlock(v, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{
+		AccessKey:    creds.AccessKey,
+		SecretKey:    creds.SecretKey,
+		SessionToken: creds.SessionToken,
+	}
+	if data, err := sourceFor(&p.source, p.ConfigPath).profile(profile); err == nil {
+		cfg.ApiURL = data["ncloud_api_url"]
+		cfg.Region = data["ncloud_region"]
+	}
+	return cfg, nil
+}
+
+func (p *VaultProvider) unlock(v *vault.Vault, profile string) (*vault.Credentials, error) {
+	if p.Agent != nil {
+		if creds, err := p.Agent.Get(profile); err == nil {
+			return creds, nil
+		}
+	}
+
+	if p.Prompt == nil {
+		return nil, fmt.Errorf("vault is locked; run 'kubectl nks-ctx credentials unlock --profile %s'", profile)
+	}
+	passphrase, err := p.Prompt(profile)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := v.Get(profile, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Agent != nil {
+		if err := p.Agent.Put(profile, *creds, p.Timeout); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: credentials will not be cached: %v\n", err)
+		}
+	}
+	return creds, nil
+}
+
+func promptPassphrase(profile string) (string, error) {
+	return vault.ReadSecret(fmt.Sprintf("Vault passphrase for profile '%s': ", profile))
+}
@@ -0,0 +1,47 @@
+package ncp
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// MinAuthenticatorVersion is the oldest ncp-iam-authenticator release
+// nks-ctx supports.
+var MinAuthenticatorVersion = Version{Major: 1, Minor: 0, Patch: 0}
+
+// Version is a semantic version of ncp-iam-authenticator.
+type Version struct {
+	Major, Minor, Patch int
+}
+
+var versionPattern = regexp.MustCompile(`v?(\d+)\.(\d+)\.(\d+)`)
+
+// ParseVersion extracts the first x.y.z version from the output of
+// `ncp-iam-authenticator version`, which may be plain text or JSON.
+func ParseVersion(output string) (Version, error) {
+	m := versionPattern.FindStringSubmatch(output)
+	if m == nil {
+		return Version{}, fmt.Errorf("no version in %q", output)
+	}
+	var v Version
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	v.Patch, _ = strconv.Atoi(m[3])
+	return v, nil
+}
+
+// Less reports whether v is older than o.
+func (v Version) Less(o Version) bool {
+	if v.Major != o.Major {
+		return v.Major < o.Major
+	}
+	if v.Minor != o.Minor {
+		return v.Minor < o.Minor
+	}
+	return v.Patch < o.Patch
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
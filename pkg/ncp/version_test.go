@@ -0,0 +1,82 @@
+package ncp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		output  string
+		want    Version
+		wantErr bool
+	}{
+		{"1.0.5", Version{1, 0, 5}, false},
+		{"ncp-iam-authenticator version v1.2.3\n", Version{1, 2, 3}, false},
+		{`{"Version":"v0.9.12","Commit":"abc1234"}`, Version{0, 9, 12}, false},
+		{"unknown", Version{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseVersion(tt.output)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseVersion(%q) error = %v, wantErr %v", tt.output, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseVersion(%q) = %v, want %v", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestVersion_Less(t *testing.T) {
+	tests := []struct {
+		a, b Version
+		want bool
+	}{
+		{Version{0, 9, 9}, Version{1, 0, 0}, true},
+		{Version{1, 0, 0}, Version{1, 0, 0}, false},
+		{Version{1, 2, 0}, Version{1, 10, 0}, true},
+		{Version{1, 0, 2}, Version{1, 0, 1}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Less(tt.b); got != tt.want {
+			t.Errorf("%v.Less(%v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestAuthenticator_CheckVersion(t *testing.T) {
+	dir := t.TempDir()
+	script := func(name, output string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("#!/bin/sh\necho '"+output+"'\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	current := NewAuthenticatorForPath("", script("current", `{"Version":"v1.1.0"}`))
+	if v, err := current.CheckVersion(); err != nil || v != (Version{1, 1, 0}) {
+		t.Errorf("CheckVersion() = %v, %v; want 1.1.0, nil", v, err)
+	}
+
+	old := NewAuthenticatorForPath("", script("old", "v0.5.0"))
+	if _, err := old.CheckVersion(); err == nil {
+		t.Error("CheckVersion() expected error for old version")
+	}
+}
+
+func TestNewAuthenticatorForPath_Absolute(t *testing.T) {
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "ncp-iam-authenticator")
+	if err := os.WriteFile(bin, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+
+	a := NewAuthenticatorForPath("", "ncp-iam-authenticator")
+	if a.Path() != bin {
+		t.Errorf("Path() = %v, want %v", a.Path(), bin)
+	}
+}
@@ -0,0 +1,125 @@
+// Package selector parses cluster selectors such as
+// "region=KR,name=~prod-.*" and matches them against cluster labels.
+package selector
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Keys are the labels a selector may refer to.
+var Keys = []string{"name", "context", "region", "profile", "status"}
+
+// Selector is a conjunction of terms; an empty Selector matches everything.
+type Selector struct {
+	terms []term
+}
+
+type term struct {
+	key   string
+	op    string // "=", "!=", "=~" or "!~"
+	value string
+	re    *regexp.Regexp
+}
+
+// operators are tried longest first so that "!=" is not read as "!" + "=".
+var operators = []string{"!=", "=~", "!~", "="}
+
+// Parse parses a comma-separated list of key=value, key!=value, key=~regex
+// and key!~regex terms. Regular expressions must match the whole value.
+func Parse(expr string) (Selector, error) {
+	var s Selector
+	for _, part := range strings.Split(expr, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		t, err := parseTerm(part)
+		if err != nil {
+			return Selector{}, err
+		}
+		s.terms = append(s.terms, t)
+	}
+	return s, nil
+}
+
+func parseTerm(part string) (term, error) {
+	idx, op := -1, ""
+	for _, candidate := range operators {
+		if i := strings.Index(part, candidate); i >= 0 && (idx < 0 || i < idx) {
+			idx, op = i, candidate
+		}
+	}
+	if idx <= 0 {
+		return term{}, fmt.Errorf("invalid selector term %q: want key=value, key!=value, key=~regex or key!~regex", part)
+	}
+
+	t := term{key: strings.TrimSpace(part[:idx]), op: op, value: strings.TrimSpace(part[idx+len(op):])}
+	if !validKey(t.key) {
+		return term{}, fmt.Errorf("invalid selector key %q: want one of %s", t.key, strings.Join(Keys, ", "))
+	}
+	if op == "=~" || op == "!~" {
+		re, err := regexp.Compile("^(?:" + t.value + ")$")
+		if err != nil {
+			return term{}, fmt.Errorf("invalid selector regex %q: %w", t.value, err)
+		}
+		t.re = re
+	}
+	return t, nil
+}
+
+func validKey(key string) bool {
+	for _, k := range Keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Empty reports whether s has no terms.
+func (s Selector) Empty() bool {
+	return len(s.terms) == 0
+}
+
+// Uses reports whether any term refers to key.
+func (s Selector) Uses(key string) bool {
+	for _, t := range s.terms {
+		if t.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether labels satisfy every term. Missing labels are "".
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, t := range s.terms {
+		v := labels[t.key]
+		var ok bool
+		switch t.op {
+		case "=":
+			ok = v == t.value
+		case "!=":
+			ok = v != t.value
+		case "=~":
+			ok = t.re.MatchString(v)
+		case "!~":
+			ok = !t.re.MatchString(v)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the selector in its canonical form.
+func (s Selector) String() string {
+	parts := make([]string, len(s.terms))
+	for i, t := range s.terms {
+		parts[i] = t.key + t.op + t.value
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,56 @@
+package selector
+
+import "testing"
+
+func TestParse_Matches(t *testing.T) {
+	labels := map[string]string{"name": "prod-api", "region": "KR", "profile": "finance"}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"", true},
+		{"region=KR", true},
+		{"region=SGN", false},
+		{"region!=SGN", true},
+		{"name=~prod-.*", true},
+		{"name=~prod", false}, // regexes match the whole value
+		{"name!~dev-.*", true},
+		{"region=KR, name=~prod-.*", true},
+		{"region=KR,profile=DEFAULT", false},
+		{"context=", true}, // missing labels are empty
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.expr, err)
+		}
+		if got := s.Matches(labels); got != tt.want {
+			t.Errorf("Parse(%q).Matches() = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, expr := range []string{"region", "=KR", "zone=a", "state=RUNNING", "name=~(", "region:KR"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) expected error", expr)
+		}
+	}
+}
+
+func TestSelector_String(t *testing.T) {
+	s, err := Parse(" region = KR , name=~prod-.* ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.String(); got != "region=KR,name=~prod-.*" {
+		t.Errorf("String() = %q", got)
+	}
+	if s.Empty() {
+		t.Error("Empty() = true")
+	}
+	if !s.Uses("region") || s.Uses("status") {
+		t.Error("Uses() = wrong keys")
+	}
+}
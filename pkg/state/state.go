@@ -0,0 +1,244 @@
+// Package state keeps what nks-ctx remembers between runs, such as the
+// history of context switches.
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// MaxHistory is how many switches are kept.
+const MaxHistory = 50
+
+// State is the nks-ctx state file.
+type State struct {
+	path string
+
+	// History lists context switches, most recent last.
+	History []Switch `json:"history,omitempty"`
+	// Namespaces maps clusters to the namespace last used in them.
+	Namespaces map[string]string `json:"namespaces,omitempty"`
+	// Reverts lists pending switches back, at most one per kubeconfig.
+	Reverts []Revert `json:"reverts,omitempty"`
+}
+
+// Revert is a pending switch of kubeconfig from Context back to To at At.
+// It only applies if Context is still current then.
+type Revert struct {
+	Kubeconfig string    `json:"kubeconfig"`
+	Context    string    `json:"context"`
+	To         string    `json:"to"`
+	At         time.Time `json:"at"`
+}
+
+// Switch is one change of current-context made by nks-ctx.
+type Switch struct {
+	Kubeconfig string    `json:"kubeconfig"`
+	From       string    `json:"from,omitempty"`
+	Context    string    `json:"context"`
+	Time       time.Time `json:"time"`
+}
+
+// Dir returns the state directory ($XDG_STATE_HOME/nks-ctx or ~/.local/state/nks-ctx).
+func Dir() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "nks-ctx")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".local", "state", "nks-ctx")
+}
+
+// DefaultPath returns the state file path, or NKS_CTX_STATE if set.
+func DefaultPath() string {
+	if p := os.Getenv("NKS_CTX_STATE"); p != "" {
+		return p
+	}
+	return filepath.Join(Dir(), "state.json")
+}
+
+// Load reads the state file at path. A missing file yields an empty state.
+func Load(path string) (*State, error) {
+	s := &State{path: path}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, s); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Update loads the state file at path, applies fn and saves the result. It
+// holds an exclusive lock for the whole load-modify-save, so concurrent
+// nks-ctx processes do not lose each other's changes. Nothing is saved if fn
+// fails.
+func Update(path string, fn func(*State) error) error {
+	unlock, err := Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	s, err := Load(path)
+	if err != nil {
+		return err
+	}
+	if err := fn(s); err != nil {
+		return err
+	}
+	return s.Save()
+}
+
+// Lock takes an exclusive lock on path+".lock", waiting for other holders,
+// and returns the function that releases it. Other nks-ctx files that are
+// updated in place use it too.
+func Lock(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+// Save writes the state file through a temporary file, so concurrent readers
+// never see a partial write. Use Update to change the file safely while
+// other processes may write it.
+func (s *State) Save() error {
+	raw, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".state-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(raw, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// RecordSwitch appends a switch, keeping the last MaxHistory.
+func (s *State) RecordSwitch(kubeconfig, from, to string, at time.Time) {
+	s.History = append(s.History, Switch{Kubeconfig: kubeconfig, From: from, Context: to, Time: at})
+	if len(s.History) > MaxHistory {
+		s.History = s.History[len(s.History)-MaxHistory:]
+	}
+}
+
+// Recent returns the switches made in kubeconfig, most recent first.
+func (s *State) Recent(kubeconfig string) []Switch {
+	var recent []Switch
+	for i := len(s.History) - 1; i >= 0; i-- {
+		if s.History[i].Kubeconfig == kubeconfig {
+			recent = append(recent, s.History[i])
+		}
+	}
+	return recent
+}
+
+// Previous returns the context to go back to from current, like `kubectx -`:
+// where the last switch came from, or the context it switched to if
+// current-context has been changed outside nks-ctx since.
+func (s *State) Previous(kubeconfig, current string) (string, bool) {
+	recent := s.Recent(kubeconfig)
+	if len(recent) == 0 {
+		return "", false
+	}
+	last := recent[0]
+	if last.Context != current {
+		return last.Context, true
+	}
+	return last.From, last.From != ""
+}
+
+// LastNamespace returns the namespace last used in cluster, or "".
+func (s *State) LastNamespace(cluster string) string {
+	return s.Namespaces[cluster]
+}
+
+// RememberNamespace records the namespace used in cluster.
+func (s *State) RememberNamespace(cluster, namespace string) {
+	if s.Namespaces == nil {
+		s.Namespaces = make(map[string]string)
+	}
+	s.Namespaces[cluster] = namespace
+}
+
+// SetRevert schedules r, replacing any pending revert of its kubeconfig.
+func (s *State) SetRevert(r Revert) {
+	s.ClearRevert(r.Kubeconfig)
+	s.Reverts = append(s.Reverts, r)
+}
+
+// ClearRevert drops the pending revert of kubeconfig. It reports whether
+// there was one.
+func (s *State) ClearRevert(kubeconfig string) bool {
+	kept := s.Reverts[:0]
+	for _, r := range s.Reverts {
+		if r.Kubeconfig != kubeconfig {
+			kept = append(kept, r)
+		}
+	}
+	cleared := len(kept) != len(s.Reverts)
+	s.Reverts = kept
+	return cleared
+}
+
+// PendingRevert returns the pending revert of kubeconfig.
+func (s *State) PendingRevert(kubeconfig string) (Revert, bool) {
+	for _, r := range s.Reverts {
+		if r.Kubeconfig == kubeconfig {
+			return r, true
+		}
+	}
+	return Revert{}, false
+}
+
+// DueReverts returns the reverts due at now.
+func (s *State) DueReverts(now time.Time) []Revert {
+	var due []Revert
+	for _, r := range s.Reverts {
+		if !r.At.After(now) {
+			due = append(due, r)
+		}
+	}
+	return due
+}
+
+// NextRevert returns the time of the earliest pending revert.
+func (s *State) NextRevert() (time.Time, bool) {
+	var next time.Time
+	for _, r := range s.Reverts {
+		if next.IsZero() || r.At.Before(next) {
+			next = r.At
+		}
+	}
+	return next, !next.IsZero()
+}
@@ -0,0 +1,155 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestState_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nks-ctx", "state.json")
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() of missing file error = %v", err)
+	}
+
+	at := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	s.RecordSwitch("/kube/config", "dev", "prod", at)
+	s.RememberNamespace("uuid-1", "payments")
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("state file mode = %v, %v", info.Mode().Perm(), err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.History) != 1 || loaded.History[0].Context != "prod" || !loaded.History[0].Time.Equal(at) {
+		t.Errorf("History = %+v", loaded.History)
+	}
+	if got := loaded.LastNamespace("uuid-1"); got != "payments" {
+		t.Errorf("LastNamespace() = %q, want payments", got)
+	}
+	if got := loaded.LastNamespace("other"); got != "" {
+		t.Errorf("LastNamespace(other) = %q", got)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestState_Previous(t *testing.T) {
+	s := &State{}
+	if _, ok := s.Previous("/kube/config", "dev"); ok {
+		t.Error("Previous() with no history = ok")
+	}
+
+	now := time.Now()
+	s.RecordSwitch("/kube/config", "dev", "prod", now)
+	s.RecordSwitch("/other", "a", "b", now)
+
+	tests := []struct {
+		current string
+		want    string
+	}{
+		{"prod", "dev"},     // back to where the last switch came from
+		{"staging", "prod"}, // current-context changed outside nks-ctx
+	}
+	for _, tt := range tests {
+		if got, ok := s.Previous("/kube/config", tt.current); !ok || got != tt.want {
+			t.Errorf("Previous(%q) = %q, %v; want %q", tt.current, got, ok, tt.want)
+		}
+	}
+
+	if recent := s.Recent("/kube/config"); len(recent) != 1 || recent[0].Context != "prod" {
+		t.Errorf("Recent() = %+v", recent)
+	}
+}
+
+func TestUpdate_Concurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nks-ctx", "state.json")
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := Update(path, func(s *State) error {
+				s.RememberNamespace(fmt.Sprintf("uuid-%d", i), "default")
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Namespaces) != 20 {
+		t.Errorf("len(Namespaces) = %d, want 20: concurrent updates were lost", len(s.Namespaces))
+	}
+
+	if err := Update(path, func(s *State) error {
+		s.RememberNamespace("uuid-0", "changed")
+		return errors.New("abort")
+	}); err == nil {
+		t.Error("Update() error = nil, want fn's error")
+	}
+	if s, _ := Load(path); s.LastNamespace("uuid-0") != "default" {
+		t.Error("Update() saved changes after fn failed")
+	}
+}
+
+func TestState_RecordSwitch_Bounded(t *testing.T) {
+	s := &State{}
+	for i := 0; i < MaxHistory+5; i++ {
+		s.RecordSwitch("/kube/config", "", string(rune('a'+i%26)), time.Now())
+	}
+	if len(s.History) != MaxHistory {
+		t.Errorf("len(History) = %d, want %d", len(s.History), MaxHistory)
+	}
+}
+
+func TestState_Reverts(t *testing.T) {
+	s := &State{}
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	if _, ok := s.NextRevert(); ok {
+		t.Error("NextRevert() with none pending = ok")
+	}
+
+	s.SetRevert(Revert{Kubeconfig: "/a", Context: "prod", To: "dev", At: now.Add(time.Hour)})
+	s.SetRevert(Revert{Kubeconfig: "/b", Context: "prod", To: "dev", At: now.Add(time.Minute)})
+	s.SetRevert(Revert{Kubeconfig: "/a", Context: "prod", To: "staging", At: now.Add(30 * time.Minute)})
+
+	if len(s.Reverts) != 2 {
+		t.Fatalf("Reverts = %+v, want one per kubeconfig", s.Reverts)
+	}
+	if r, ok := s.PendingRevert("/a"); !ok || r.To != "staging" {
+		t.Errorf("PendingRevert(/a) = %+v, %v", r, ok)
+	}
+	if next, _ := s.NextRevert(); !next.Equal(now.Add(time.Minute)) {
+		t.Errorf("NextRevert() = %v", next)
+	}
+	if due := s.DueReverts(now.Add(time.Minute)); len(due) != 1 || due[0].Kubeconfig != "/b" {
+		t.Errorf("DueReverts() = %+v", due)
+	}
+
+	if !s.ClearRevert("/b") || s.ClearRevert("/b") {
+		t.Error("ClearRevert() should report only the first removal")
+	}
+	if _, ok := s.PendingRevert("/b"); ok {
+		t.Error("PendingRevert(/b) after ClearRevert")
+	}
+}
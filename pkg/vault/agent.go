@@ -0,0 +1,309 @@
+package vault
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// AgentArgs are the arguments that make the plugin binary run Serve.
+// AgentClient.Put re-executes the current binary with these arguments
+// when no agent is listening.
+var AgentArgs = []string{"credentials", "agent"}
+
+// DefaultTimeout is how long unlocked credentials stay cached in the agent.
+const DefaultTimeout = 15 * time.Minute
+
+// DefaultSocketPath returns the per-user agent socket path,
+// or the value of NKS_CTX_AGENT_SOCKET if set. The socket lives in
+// $XDG_RUNTIME_DIR when set, which only the user can access.
+func DefaultSocketPath() string {
+	if p := os.Getenv("NKS_CTX_AGENT_SOCKET"); p != "" {
+		return p
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "nks-ctx", "agent.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("nks-ctx-%d", os.Getuid()), "agent.sock")
+}
+
+// checkSocketDir refuses a socket directory that another user could have
+// created or can write to: it must be a real directory, not a symlink,
+// owned by the current user with mode 0700. Otherwise another local user
+// could listen on the socket and receive the credentials sent to it.
+func checkSocketDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("agent socket directory %s is not a directory", dir)
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok && int(st.Uid) != os.Getuid() {
+		return fmt.Errorf("agent socket directory %s is owned by uid %d, not %d", dir, st.Uid, os.Getuid())
+	}
+	if perm := info.Mode().Perm(); perm != 0700 {
+		return fmt.Errorf("agent socket directory %s has mode %#o, want 0700", dir, perm)
+	}
+	return nil
+}
+
+type agentRequest struct {
+	Op          string       `json:"op"`
+	Profile     string       `json:"profile,omitempty"`
+	Credentials *Credentials `json:"credentials,omitempty"`
+	TTL         Duration     `json:"ttl,omitempty"`
+}
+
+type agentResponse struct {
+	Credentials *Credentials `json:"credentials,omitempty"`
+	Error       string       `json:"error,omitempty"`
+}
+
+// Duration is a time.Duration that marshals as a string ("15m0s").
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+type cachedCredentials struct {
+	creds   Credentials
+	expires time.Time
+}
+
+// Agent caches unlocked credentials in memory, keyed by profile.
+// Entries expire after their TTL; the agent exits once it holds no entries.
+type Agent struct {
+	mu      sync.Mutex
+	entries map[string]cachedCredentials
+	now     func() time.Time
+}
+
+// Serve runs the agent on a unix socket until every cached entry has expired.
+func Serve(socketPath string) error {
+	dir := filepath.Dir(socketPath)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	if err := checkSocketDir(dir); err != nil {
+		return err
+	}
+	os.Remove(socketPath)
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", socketPath, err)
+	}
+	defer os.Remove(socketPath)
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		listener.Close()
+		return err
+	}
+
+	agent := &Agent{entries: make(map[string]cachedCredentials), now: time.Now}
+
+	// Give the first client a moment to store credentials before idling out.
+	idle := time.Now().Add(30 * time.Second)
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			if agent.prune() == 0 && time.Now().After(idle) {
+				listener.Close()
+				return
+			}
+		}
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return nil
+		}
+		go agent.handle(conn)
+	}
+}
+
+func (a *Agent) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	var req agentRequest
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&req); err != nil {
+		return
+	}
+	json.NewEncoder(conn).Encode(a.dispatch(req))
+}
+
+func (a *Agent) dispatch(req agentRequest) agentResponse {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	switch req.Op {
+	case "get":
+		c, ok := a.entries[req.Profile]
+		if !ok || a.now().After(c.expires) {
+			delete(a.entries, req.Profile)
+			return agentResponse{Error: "not cached"}
+		}
+		creds := c.creds
+		return agentResponse{Credentials: &creds}
+	case "put":
+		if req.Credentials == nil {
+			return agentResponse{Error: "missing credentials"}
+		}
+		ttl := time.Duration(req.TTL)
+		if ttl <= 0 {
+			ttl = DefaultTimeout
+		}
+		a.entries[req.Profile] = cachedCredentials{creds: *req.Credentials, expires: a.now().Add(ttl)}
+		return agentResponse{}
+	case "lock":
+		a.entries = make(map[string]cachedCredentials)
+		return agentResponse{}
+	}
+	return agentResponse{Error: fmt.Sprintf("unknown op %q", req.Op)}
+}
+
+// prune drops expired entries and returns how many remain.
+func (a *Agent) prune() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for profile, c := range a.entries {
+		if a.now().After(c.expires) {
+			delete(a.entries, profile)
+		}
+	}
+	return len(a.entries)
+}
+
+// AgentClient talks to a running agent.
+type AgentClient struct {
+	SocketPath string
+}
+
+// NewAgentClient returns a client for the agent at socketPath.
+func NewAgentClient(socketPath string) *AgentClient {
+	return &AgentClient{SocketPath: socketPath}
+}
+
+// Get returns cached credentials for profile, or an error if the agent
+// is not running or has nothing cached.
+func (c *AgentClient) Get(profile string) (*Credentials, error) {
+	resp, err := c.call(agentRequest{Op: "get", Profile: profile})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Credentials, nil
+}
+
+// Put caches credentials for profile for ttl, starting the agent if needed.
+func (c *AgentClient) Put(profile string, creds Credentials, ttl time.Duration) error {
+	req := agentRequest{Op: "put", Profile: profile, Credentials: &creds, TTL: Duration(ttl)}
+	_, err := c.call(req)
+	if err == nil || !isNotRunning(err) {
+		return err
+	}
+	if err := c.start(); err != nil {
+		return err
+	}
+	_, err = c.call(req)
+	return err
+}
+
+// Lock drops all cached credentials. A stopped agent is not an error.
+func (c *AgentClient) Lock() error {
+	if _, err := c.call(agentRequest{Op: "lock"}); err != nil && !isNotRunning(err) {
+		return err
+	}
+	return nil
+}
+
+func (c *AgentClient) call(req agentRequest) (*agentResponse, error) {
+	if err := checkSocketDir(filepath.Dir(c.SocketPath)); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, &notRunningError{err}
+		}
+		return nil, err
+	}
+	conn, err := net.DialTimeout("unix", c.SocketPath, time.Second)
+	if err != nil {
+		return nil, &notRunningError{err}
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, err
+	}
+	var resp agentResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("agent: %s", resp.Error)
+	}
+	return &resp, nil
+}
+
+// start launches a detached agent process and waits for its socket.
+func (c *AgentClient) start() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(exe, AgentArgs...)
+	cmd.Env = append(os.Environ(), "NKS_CTX_AGENT_SOCKET="+c.SocketPath)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start credentials agent: %w", err)
+	}
+	cmd.Process.Release()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if conn, err := net.Dial("unix", c.SocketPath); err == nil {
+			conn.Close()
+			return nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return fmt.Errorf("credentials agent did not start")
+}
+
+type notRunningError struct{ err error }
+
+func (e *notRunningError) Error() string { return "credentials agent not running: " + e.err.Error() }
+
+func (e *notRunningError) Unwrap() error { return e.err }
+
+func isNotRunning(err error) bool {
+	_, ok := err.(*notRunningError)
+	return ok
+}
@@ -0,0 +1,106 @@
+package vault
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAgent_Expiry(t *testing.T) {
+	now := time.Now()
+	agent := &Agent{entries: make(map[string]cachedCredentials), now: func() time.Time { return now }}
+
+	resp := agent.dispatch(agentRequest{Op: "put", Profile: "p", Credentials: &Credentials{AccessKey: "ak", SecretKey: "sk"}, TTL: Duration(time.Minute)})
+	if resp.Error != "" {
+		t.Fatalf("put error = %v", resp.Error)
+	}
+
+	resp = agent.dispatch(agentRequest{Op: "get", Profile: "p"})
+	if resp.Credentials == nil || resp.Credentials.AccessKey != "ak" {
+		t.Fatalf("get = %+v, want cached credentials", resp)
+	}
+
+	now = now.Add(2 * time.Minute)
+	resp = agent.dispatch(agentRequest{Op: "get", Profile: "p"})
+	if resp.Credentials != nil {
+		t.Error("get after TTL returned credentials")
+	}
+	if agent.prune() != 0 {
+		t.Error("prune() after TTL left entries")
+	}
+}
+
+func TestAgentClient_RoundTrip(t *testing.T) {
+	// Serve creates the socket directory itself with mode 0700.
+	socket := filepath.Join(t.TempDir(), "agent", "agent.sock")
+	go Serve(socket)
+
+	client := NewAgentClient(socket)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := client.call(agentRequest{Op: "lock"}); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("agent did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, err := client.Get("p"); err == nil {
+		t.Error("Get() before Put() expected error")
+	}
+	if err := client.Put("p", Credentials{AccessKey: "ak", SecretKey: "sk"}, time.Minute); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	creds, err := client.Get("p")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if creds.SecretKey != "sk" {
+		t.Errorf("SecretKey = %v, want sk", creds.SecretKey)
+	}
+
+	if err := client.Lock(); err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	if _, err := client.Get("p"); err == nil {
+		t.Error("Get() after Lock() expected error")
+	}
+}
+
+func TestAgentClient_RefusesUnsafeSocketDir(t *testing.T) {
+	open := filepath.Join(t.TempDir(), "open")
+	if err := os.Mkdir(open, 0777); err != nil {
+		t.Fatal(err)
+	}
+	os.Chmod(open, 0777)
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(t.TempDir(), link); err != nil {
+		t.Fatal(err)
+	}
+	dirs := []string{open, link}
+
+	if os.Getuid() == 0 {
+		foreign := filepath.Join(t.TempDir(), "foreign")
+		if err := os.Mkdir(foreign, 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chown(foreign, 12345, 12345); err != nil {
+			t.Fatal(err)
+		}
+		dirs = append(dirs, foreign)
+	}
+
+	for _, dir := range dirs {
+		socket := filepath.Join(dir, "agent.sock")
+		if err := Serve(socket); err == nil {
+			t.Errorf("Serve(%s) accepted an unsafe directory", dir)
+		}
+		err := NewAgentClient(socket).Put("p", Credentials{AccessKey: "ak", SecretKey: "sk"}, time.Minute)
+		if err == nil || isNotRunning(err) {
+			t.Errorf("Put() in %s = %v, want refusal", dir, err)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package vault
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// ReadSecret prompts on the controlling terminal and reads a line without echo.
+// The terminal is used directly so prompts work when kubectl owns stdin/stdout.
+func ReadSecret(prompt string) (string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", fmt.Errorf("no terminal available to prompt for %s", strings.TrimSuffix(strings.ToLower(prompt), ": "))
+	}
+	defer tty.Close()
+
+	fmt.Fprint(tty, prompt)
+	secret, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+// ReadLine prompts on the controlling terminal and reads a line with echo.
+func ReadLine(prompt string) (string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", fmt.Errorf("no terminal available to prompt for %s", strings.TrimSuffix(strings.ToLower(prompt), ": "))
+	}
+	defer tty.Close()
+
+	fmt.Fprint(tty, prompt)
+	line, err := bufio.NewReader(tty).ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
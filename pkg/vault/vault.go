@@ -0,0 +1,235 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// scrypt parameters (interactive login strength, ~100ms on a laptop).
+const (
+	scryptN       = 1 << 15
+	scryptR       = 8
+	scryptP       = 1
+	keyLength     = 32
+	saltLength    = 16
+	formatVersion = 1
+)
+
+// Limits on the scrypt parameters read from a vault file, so a corrupted or
+// hostile file cannot make every unlock use gigabytes of memory or minutes of CPU.
+const (
+	minScryptN = 1 << 14
+	maxScryptN = 1 << 20
+	maxScryptR = 16
+	maxScryptP = 4
+)
+
+// ErrNotFound is returned when the vault has no entry for a profile.
+var ErrNotFound = errors.New("profile not found in vault")
+
+// ErrBadPassphrase is returned when an entry cannot be decrypted.
+var ErrBadPassphrase = errors.New("wrong passphrase or corrupted vault entry")
+
+// Credentials is the secret payload stored per profile.
+type Credentials struct {
+	AccessKey    string `json:"access_key"`
+	SecretKey    string `json:"secret_key"`
+	SessionToken string `json:"session_token,omitempty"`
+}
+
+// Vault is an encrypted credential store, one entry per NCP profile.
+// Each entry is sealed with AES-256-GCM using a key derived from a passphrase with scrypt.
+type Vault struct {
+	path    string
+	entries map[string]entry
+}
+
+type vaultFile struct {
+	Version  int              `json:"version"`
+	Profiles map[string]entry `json:"profiles"`
+}
+
+type entry struct {
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// DefaultPath returns the vault location (~/.ncloud/nks-ctx.vault),
+// or the value of NKS_CTX_VAULT if set.
+func DefaultPath() string {
+	if p := os.Getenv("NKS_CTX_VAULT"); p != "" {
+		return p
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".ncloud", "nks-ctx.vault")
+}
+
+// Open loads the vault at path. A missing file yields an empty vault.
+func Open(path string) (*Vault, error) {
+	v := &Vault{path: path, entries: make(map[string]entry)}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return v, nil
+		}
+		return nil, err
+	}
+
+	var f vaultFile
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse vault %s: %w", path, err)
+	}
+	if f.Version != formatVersion {
+		return nil, fmt.Errorf("unsupported vault version %d in %s", f.Version, path)
+	}
+	if f.Profiles != nil {
+		v.entries = f.Profiles
+	}
+	return v, nil
+}
+
+// Has reports whether the vault holds an entry for profile.
+func (v *Vault) Has(profile string) bool {
+	_, ok := v.entries[profile]
+	return ok
+}
+
+// Profiles returns the sorted profile names stored in the vault.
+func (v *Vault) Profiles() []string {
+	names := make([]string, 0, len(v.entries))
+	for name := range v.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Put encrypts creds under passphrase and stores them for profile.
+// Call Save to persist the change.
+func (v *Vault) Put(profile, passphrase string, creds Credentials) error {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+
+	gcm, err := newGCM(passphrase, salt, scryptN, scryptR, scryptP)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	plaintext, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+
+	v.entries[profile] = entry{
+		KDF:        "scrypt",
+		N:          scryptN,
+		R:          scryptR,
+		P:          scryptP,
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, plaintext, []byte(profile)),
+	}
+	return nil
+}
+
+// Get decrypts the entry for profile with passphrase.
+func (v *Vault) Get(profile, passphrase string) (*Credentials, error) {
+	e, ok := v.entries[profile]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if e.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported kdf %q for profile '%s'", e.KDF, profile)
+	}
+	if err := checkScryptParams(e.N, e.R, e.P); err != nil {
+		return nil, fmt.Errorf("vault entry for profile '%s': %w", profile, err)
+	}
+
+	gcm, err := newGCM(passphrase, e.Salt, e.N, e.R, e.P)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := gcm.Open(nil, e.Nonce, e.Ciphertext, []byte(profile))
+	if err != nil {
+		return nil, ErrBadPassphrase
+	}
+
+	var creds Credentials
+	if err := json.Unmarshal(plaintext, &creds); err != nil {
+		return nil, ErrBadPassphrase
+	}
+	return &creds, nil
+}
+
+// Remove deletes the entry for profile. Call Save to persist the change.
+func (v *Vault) Remove(profile string) {
+	delete(v.entries, profile)
+}
+
+// Save writes the vault to disk with 0600 permissions.
+func (v *Vault) Save() error {
+	if err := os.MkdirAll(filepath.Dir(v.path), 0700); err != nil {
+		return fmt.Errorf("failed to create vault directory: %w", err)
+	}
+
+	raw, err := json.MarshalIndent(vaultFile{Version: formatVersion, Profiles: v.entries}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := v.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, v.path)
+}
+
+// checkScryptParams rejects parameters outside the limits above: N must be a
+// power of two.
+func checkScryptParams(n, r, p int) error {
+	if n < minScryptN || n > maxScryptN || n&(n-1) != 0 {
+		return fmt.Errorf("scrypt N %d is not a power of two between %d and %d", n, minScryptN, maxScryptN)
+	}
+	if r < 1 || r > maxScryptR {
+		return fmt.Errorf("scrypt r %d is not between 1 and %d", r, maxScryptR)
+	}
+	if p < 1 || p > maxScryptP {
+		return fmt.Errorf("scrypt p %d is not between 1 and %d", p, maxScryptP)
+	}
+	return nil
+}
+
+func newGCM(passphrase string, salt []byte, n, r, p int) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, n, r, p, keyLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
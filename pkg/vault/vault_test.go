@@ -0,0 +1,79 @@
+package vault
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVault_PutGetRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nks-ctx.vault")
+
+	v, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if err := v.Put("finance", "passphrase", Credentials{AccessKey: "ak", SecretKey: "super-secret-value"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := v.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("vault mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	raw, _ := os.ReadFile(path)
+	if strings.Contains(string(raw), "super-secret-value") {
+		t.Error("vault file contains plaintext secret")
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() after save error = %v", err)
+	}
+	creds, err := reopened.Get("finance", "passphrase")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if creds.AccessKey != "ak" || creds.SecretKey != "super-secret-value" {
+		t.Errorf("Get() = %+v, want stored keys", creds)
+	}
+}
+
+func TestVault_Errors(t *testing.T) {
+	v, err := Open(filepath.Join(t.TempDir(), "missing.vault"))
+	if err != nil {
+		t.Fatalf("Open() on missing file error = %v", err)
+	}
+	if _, err := v.Get("DEFAULT", "x"); err != ErrNotFound {
+		t.Errorf("Get() on empty vault error = %v, want ErrNotFound", err)
+	}
+
+	if err := v.Put("DEFAULT", "right", Credentials{AccessKey: "ak", SecretKey: "sk"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Get("DEFAULT", "wrong"); err != ErrBadPassphrase {
+		t.Errorf("Get() with wrong passphrase error = %v, want ErrBadPassphrase", err)
+	}
+
+	for _, params := range [][3]int{{1 << 30, 8, 1}, {1<<15 + 1, 8, 1}, {1 << 10, 8, 1}, {1 << 15, 1024, 1}, {1 << 15, 8, 64}} {
+		e := v.entries["DEFAULT"]
+		e.N, e.R, e.P = params[0], params[1], params[2]
+		v.entries["DEFAULT"] = e
+		if _, err := v.Get("DEFAULT", "right"); err == nil || err == ErrBadPassphrase {
+			t.Errorf("Get() with scrypt parameters %v error = %v, want rejection", params, err)
+		}
+	}
+
+	v.Remove("DEFAULT")
+	if v.Has("DEFAULT") {
+		t.Error("Has() after Remove() = true")
+	}
+}